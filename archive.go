@@ -26,7 +26,7 @@ type ArchiveOption struct {
 
 // Archive archives zip
 func (app *App) Archive(ctx context.Context, opt *ArchiveOption) error {
-	if err := opt.Expand(); err != nil {
+	if err := app.expandZipOption(&opt.ZipOption); err != nil {
 		return err
 	}
 
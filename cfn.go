@@ -0,0 +1,132 @@
+package lambroll
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// CFnLookup resolves outputs and exports of AWS CloudFormation stacks.
+type CFnLookup struct {
+	client *cloudformation.Client
+
+	outputs sync.Map // stack name => map[OutputKey]OutputValue
+
+	mu      sync.Mutex
+	exports map[string]string
+}
+
+func newCFnLookup(cfg aws.Config) *CFnLookup {
+	return &CFnLookup{
+		client: cloudformation.NewFromConfig(cfg),
+	}
+}
+
+// Output returns the output value of the stack.
+func (c *CFnLookup) Output(ctx context.Context, stack, key string) (string, error) {
+	var outputs map[string]string
+	if v, ok := c.outputs.Load(stack); ok {
+		outputs = v.(map[string]string)
+	} else {
+		log.Printf("[debug] describe stack %s", stack)
+		res, err := c.client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+			StackName: aws.String(stack),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to describe stack %s: %w", stack, err)
+		}
+		if len(res.Stacks) == 0 {
+			return "", fmt.Errorf("stack %s is not found", stack)
+		}
+		outputs = make(map[string]string, len(res.Stacks[0].Outputs))
+		for _, o := range res.Stacks[0].Outputs {
+			outputs[aws.ToString(o.OutputKey)] = aws.ToString(o.OutputValue)
+		}
+		c.outputs.Store(stack, outputs)
+	}
+	v, ok := outputs[key]
+	if !ok {
+		return "", fmt.Errorf("output %s is not found in stack %s", key, stack)
+	}
+	return v, nil
+}
+
+// Export returns the value of the exported output.
+func (c *CFnLookup) Export(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.exports == nil {
+		exports := make(map[string]string)
+		var nextToken *string
+		for {
+			log.Println("[debug] list exports")
+			res, err := c.client.ListExports(ctx, &cloudformation.ListExportsInput{
+				NextToken: nextToken,
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to list exports: %w", err)
+			}
+			for _, e := range res.Exports {
+				exports[aws.ToString(e.Name)] = aws.ToString(e.Value)
+			}
+			if nextToken = res.NextToken; nextToken == nil {
+				break
+			}
+		}
+		c.exports = exports
+	}
+	v, ok := c.exports[name]
+	if !ok {
+		return "", fmt.Errorf("export %s is not found", name)
+	}
+	return v, nil
+}
+
+func (c *CFnLookup) JsonnetNativeFuncs(ctx context.Context) []*jsonnet.NativeFunction {
+	return []*jsonnet.NativeFunction{
+		{
+			Name:   "cfn_output",
+			Params: []ast.Identifier{"stack", "key"},
+			Func: func(params []any) (any, error) {
+				stack, ok := params[0].(string)
+				if !ok {
+					return nil, fmt.Errorf("cfn_output: stack must be a string")
+				}
+				key, ok := params[1].(string)
+				if !ok {
+					return nil, fmt.Errorf("cfn_output: key must be a string")
+				}
+				return c.Output(ctx, stack, key)
+			},
+		},
+		{
+			Name:   "cfn_export",
+			Params: []ast.Identifier{"name"},
+			Func: func(params []any) (any, error) {
+				name, ok := params[0].(string)
+				if !ok {
+					return nil, fmt.Errorf("cfn_export: name must be a string")
+				}
+				return c.Export(ctx, name)
+			},
+		},
+	}
+}
+
+func (c *CFnLookup) FuncMap(ctx context.Context) template.FuncMap {
+	return template.FuncMap{
+		"cfn_output": func(stack, key string) (string, error) {
+			return c.Output(ctx, stack, key)
+		},
+		"cfn_export": func(name string) (string, error) {
+			return c.Export(ctx, name)
+		},
+	}
+}
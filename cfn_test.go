@@ -0,0 +1,86 @@
+package lambroll_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testDescribeStacksResponse = `<DescribeStacksResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DescribeStacksResult>
+    <Stacks>
+      <member>
+        <StackName>%s</StackName>
+        <Outputs>
+          <member>
+            <OutputKey>RoleArn</OutputKey>
+            <OutputValue>arn:aws:iam::123456789012:role/test_lambda_role</OutputValue>
+          </member>
+        </Outputs>
+      </member>
+    </Stacks>
+  </DescribeStacksResult>
+</DescribeStacksResponse>`
+
+const testListExportsResponse = `<ListExportsResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListExportsResult>
+    <Exports>
+      <member>
+        <ExportingStackId>arn:aws:cloudformation:ap-northeast-1:123456789012:stack/network/xxx</ExportingStackId>
+        <Name>network-SubnetId</Name>
+        <Value>subnet-08dc9a51660120991</Value>
+      </member>
+    </Exports>
+  </ListExportsResult>
+</ListExportsResponse>`
+
+func TestCFnLookup(t *testing.T) {
+	calls := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		action := r.Form.Get("Action")
+		calls[action]++
+		w.Header().Set("Content-Type", "text/xml")
+		switch action {
+		case "DescribeStacks":
+			fmt.Fprintf(w, testDescribeStacksResponse, r.Form.Get("StackName"))
+		case "ListExports":
+			fmt.Fprint(w, testListExportsResponse)
+		default:
+			t.Errorf("unexpected action %s", action)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	app := newTestApp(t, ts)
+	ctx := context.Background()
+	c := app.CFnLookup()
+	for i := 0; i < 2; i++ {
+		if v, err := c.Output(ctx, "app", "RoleArn"); err != nil {
+			t.Error(err)
+		} else if v != "arn:aws:iam::123456789012:role/test_lambda_role" {
+			t.Errorf("unexpected output value: %s", v)
+		}
+		if v, err := c.Export(ctx, "network-SubnetId"); err != nil {
+			t.Error(err)
+		} else if v != "subnet-08dc9a51660120991" {
+			t.Errorf("unexpected export value: %s", v)
+		}
+	}
+	if _, err := c.Output(ctx, "app", "NotFound"); err == nil {
+		t.Error("expected error for missing output")
+	}
+	if _, err := c.Export(ctx, "NotFound"); err == nil {
+		t.Error("expected error for missing export")
+	}
+	if calls["DescribeStacks"] != 1 || calls["ListExports"] != 1 {
+		t.Errorf("lookups must be cached: %v", calls)
+	}
+}
@@ -2,6 +2,7 @@ package lambroll
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -25,23 +26,34 @@ type Option struct {
 	Envfile         []string          `help:"environment files" env:"LAMBROLL_ENVFILE"`
 	ExtStr          map[string]string `help:"external string values for Jsonnet" env:"LAMBROLL_EXTSTR"`
 	ExtCode         map[string]string `help:"external code values for Jsonnet" env:"LAMBROLL_EXTCODE"`
+
+	Env               string `help:"environment name to merge the overlay definition (e.g. function.{env}.json)" env:"LAMBROLL_ENV"`
+	OverlayArrayMerge string `help:"how to merge arrays in the overlay definition (replace,append)" default:"replace" enum:"replace,append" env:"LAMBROLL_OVERLAY_ARRAY_MERGE"`
+
+	MaskKeys []string `help:"key patterns to mask the values in outputs (e.g. *_TOKEN,*PASSWORD*)" env:"LAMBROLL_MASK_KEYS"`
 }
 
 type CLIOptions struct {
 	Option
 
-	Deploy   *DeployOption   `cmd:"deploy" help:"deploy or create function"`
-	Init     *InitOption     `cmd:"init" help:"init function.json"`
-	List     *ListOption     `cmd:"list" help:"list functions"`
-	Rollback *RollbackOption `cmd:"rollback" help:"rollback function"`
-	Invoke   *InvokeOption   `cmd:"invoke" help:"invoke function"`
-	Archive  *ArchiveOption  `cmd:"archive" help:"archive function"`
-	Logs     *LogsOption     `cmd:"logs" help:"show logs of function"`
-	Diff     *DiffOption     `cmd:"diff" help:"show diff of function"`
-	Render   *RenderOption   `cmd:"render" help:"render function.json"`
-	Status   *StatusOption   `cmd:"status" help:"show status of function"`
-	Delete   *DeleteOption   `cmd:"delete" help:"delete function"`
-	Versions *VersionsOption `cmd:"versions" help:"show versions of function"`
+	Deploy   *DeployOption          `cmd:"deploy" help:"deploy or create function"`
+	Init     *InitOption            `cmd:"init" help:"init function.json"`
+	List     *ListOption            `cmd:"list" help:"list functions"`
+	Rollback *RollbackOption        `cmd:"rollback" help:"rollback function"`
+	Invoke   *InvokeOption          `cmd:"invoke" help:"invoke function"`
+	Archive  *ArchiveOption         `cmd:"archive" help:"archive function"`
+	Logs     *LogsCommandOption     `cmd:"logs" help:"show logs of function"`
+	Diff     *DiffOption            `cmd:"diff" help:"show diff of function"`
+	Render   *RenderOption          `cmd:"render" help:"render function.json"`
+	Status   *StatusOption          `cmd:"status" help:"show status of function"`
+	Delete   *DeleteOption          `cmd:"delete" help:"delete function"`
+	Versions *VersionsCommandOption `cmd:"versions" help:"manage versions of function"`
+	Export   *ExportOption          `cmd:"export" help:"export function as SAM/CloudFormation template or Terraform HCL"`
+	Import   *ImportOption          `cmd:"import" help:"import function from SAM/CloudFormation template"`
+	Drift    *DriftOption           `cmd:"drift" help:"detect drift of functions"`
+	Curl     *CurlOption            `cmd:"curl" help:"send HTTP request to the function URL"`
+	Local    *LocalOption           `cmd:"local" help:"run function locally"`
+	Metrics  *MetricsOption         `cmd:"metrics" help:"show CloudWatch metrics of function"`
 
 	Version struct{} `cmd:"version" help:"show version"`
 }
@@ -63,7 +75,15 @@ func ParseCLI(args []string) (string, *CLIOptions, func(), error) {
 	if err != nil {
 		return "", nil, nil, fmt.Errorf("failed to parse args: %w", err)
 	}
-	sub := strings.Fields(c.Command())[0]
+	// subcommand without arguments (e.g. "logs query <query>" -> "logs query")
+	var words []string
+	for _, w := range strings.Fields(c.Command()) {
+		if strings.HasPrefix(w, "<") {
+			break
+		}
+		words = append(words, w)
+	}
+	sub := strings.Join(words, " ")
 	return sub, &opts, func() { c.PrintUsage(true) }, nil
 }
 
@@ -89,11 +109,33 @@ func CLI(ctx context.Context, parse CLIParseFunc) (int, error) {
 	log.SetOutput(filter)
 
 	if err := dispatchCLI(ctx, sub, usage, opts); err != nil {
+		var ee *ExitCodeError
+		if errors.As(err, &ee) {
+			return ee.Code, ee.Err
+		}
 		return 1, err
 	}
 	return 0, nil
 }
 
+// ExitCodeError represents an error with the exit code of the command.
+// Err may be nil when the command exits with non-zero code without an error message.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
 func dispatchCLI(ctx context.Context, sub string, usage func(), opts *CLIOptions) error {
 	switch sub {
 	case "version", "":
@@ -119,10 +161,16 @@ func dispatchCLI(ctx context.Context, sub string, usage func(), opts *CLIOptions
 		return app.Deploy(ctx, opts.Deploy)
 	case "invoke":
 		return app.Invoke(ctx, opts.Invoke)
-	case "logs":
-		return app.Logs(ctx, opts.Logs)
-	case "versions":
-		return app.Versions(ctx, opts.Versions)
+	case "logs tail":
+		return app.Logs(ctx, opts.Logs.Tail)
+	case "logs query":
+		return app.LogsQuery(ctx, opts.Logs.Query)
+	case "versions list":
+		return app.Versions(ctx, opts.Versions.List)
+	case "versions prune":
+		return app.PruneVersions(ctx, opts.Versions.Prune)
+	case "versions diff":
+		return app.DiffVersions(ctx, opts.Versions.Diff)
 	case "archive":
 		return app.Archive(ctx, opts.Archive)
 	case "rollback":
@@ -135,6 +183,18 @@ func dispatchCLI(ctx context.Context, sub string, usage func(), opts *CLIOptions
 		return app.Delete(ctx, opts.Delete)
 	case "status":
 		return app.Status(ctx, opts.Status)
+	case "export":
+		return app.Export(ctx, opts.Export)
+	case "import":
+		return app.Import(ctx, opts.Import)
+	case "drift":
+		return app.Drift(ctx, opts.Drift)
+	case "curl":
+		return app.Curl(ctx, opts.Curl)
+	case "local invoke":
+		return app.LocalInvoke(ctx, opts.Local.Invoke)
+	case "metrics":
+		return app.Metrics(ctx, opts.Metrics)
 	default:
 		usage()
 	}
@@ -0,0 +1,146 @@
+package lambroll
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/kylelemons/godebug/diff"
+)
+
+// codeDiffMaxTextSize is the max size of files to show text diffs
+const codeDiffMaxTextSize = 64 * 1024
+
+const (
+	codeFileAdded    = "added"
+	codeFileRemoved  = "removed"
+	codeFileModified = "modified"
+)
+
+// codeFileChange represents a changed file between the deployed package and the local archive.
+type codeFileChange struct {
+	Name    string `json:"Name"`
+	Status  string `json:"Status"`
+	OldSize int64  `json:"OldSize,omitempty"`
+	NewSize int64  `json:"NewSize,omitempty"`
+	Diff    string `json:"Diff,omitempty"`
+}
+
+func (c *codeFileChange) String() string {
+	switch c.Status {
+	case codeFileAdded:
+		return fmt.Sprintf("A %s (%d bytes)", c.Name, c.NewSize)
+	case codeFileRemoved:
+		return fmt.Sprintf("D %s (%d bytes)", c.Name, c.OldSize)
+	default:
+		return fmt.Sprintf("M %s (%d -> %d bytes)", c.Name, c.OldSize, c.NewSize)
+	}
+}
+
+// downloadCode downloads the deployed package from the location to a temporary file.
+func downloadCode(location string) (string, error) {
+	tmp, err := os.CreateTemp("", "lambroll-code-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmp.Close()
+	log.Println("[info] downloading the deployed package")
+	if err := download(location, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// diffCodeFiles compares the entries of zip archives file by file.
+func diffCodeFiles(remote, local *zip.Reader) ([]*codeFileChange, error) {
+	remoteFiles := zipFileMap(remote)
+	localFiles := zipFileMap(local)
+
+	names := make([]string, 0, len(remoteFiles)+len(localFiles))
+	for name := range remoteFiles {
+		names = append(names, name)
+	}
+	for name := range localFiles {
+		if _, ok := remoteFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []*codeFileChange
+	for _, name := range names {
+		r, l := remoteFiles[name], localFiles[name]
+		switch {
+		case r == nil:
+			changes = append(changes, &codeFileChange{Name: name, Status: codeFileAdded, NewSize: int64(l.UncompressedSize64)})
+		case l == nil:
+			changes = append(changes, &codeFileChange{Name: name, Status: codeFileRemoved, OldSize: int64(r.UncompressedSize64)})
+		case r.CRC32 != l.CRC32 || r.UncompressedSize64 != l.UncompressedSize64 || r.Mode() != l.Mode():
+			c := &codeFileChange{
+				Name:    name,
+				Status:  codeFileModified,
+				OldSize: int64(r.UncompressedSize64),
+				NewSize: int64(l.UncompressedSize64),
+			}
+			ds, err := textDiffOfZipFiles(r, l)
+			if err != nil {
+				return nil, err
+			}
+			if ds == "" && r.Mode() != l.Mode() {
+				ds = fmt.Sprintf("-mode %s\n+mode %s", r.Mode(), l.Mode())
+			}
+			c.Diff = ds
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func zipFileMap(r *zip.Reader) map[string]*zip.File {
+	m := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		m[f.Name] = f
+	}
+	return m
+}
+
+// textDiffOfZipFiles returns the text diff of files. It returns empty string for large or binary files.
+func textDiffOfZipFiles(r, l *zip.File) (string, error) {
+	if r.UncompressedSize64 > codeDiffMaxTextSize || l.UncompressedSize64 > codeDiffMaxTextSize {
+		return "", nil
+	}
+	rb, err := readZipFile(r)
+	if err != nil {
+		return "", err
+	}
+	lb, err := readZipFile(l)
+	if err != nil {
+		return "", err
+	}
+	if !isText(rb) || !isText(lb) {
+		return "", nil
+	}
+	return diff.Diff(string(rb), string(lb)), nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in zip: %w", f.Name, err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func isText(b []byte) bool {
+	return utf8.Valid(b) && !bytes.ContainsRune(b, 0)
+}
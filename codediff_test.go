@@ -0,0 +1,64 @@
+package lambroll_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fujiwara/lambroll"
+)
+
+func newTestZip(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, body := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestDiffCodeFiles(t *testing.T) {
+	remote := newTestZip(t, map[string]string{
+		"index.js":      "exports.handler = async () => {\n  return 'hello';\n};\n",
+		"lib/util.js":   "module.exports = {};\n",
+		"bin/bootstrap": "\x00\x01\x02",
+		"README.md":     "same\n",
+	})
+	local := newTestZip(t, map[string]string{
+		"index.js":      "exports.handler = async () => {\n  return 'world';\n};\n",
+		"lib/new.js":    "module.exports = {};\n",
+		"bin/bootstrap": "\x00\x01\x03",
+		"README.md":     "same\n",
+	})
+	changes, err := lambroll.DiffCodeFiles(remote, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Status+" "+c.Name)
+	}
+	expected := "modified bin/bootstrap,modified index.js,added lib/new.js,removed lib/util.js"
+	if s := strings.Join(got, ","); s != expected {
+		t.Errorf("unexpected changes: %s, expected %s", s, expected)
+	}
+	if changes[0].Diff != "" {
+		t.Errorf("binary file should not have a text diff: %s", changes[0].Diff)
+	}
+	if !strings.Contains(changes[1].Diff, "-  return 'hello';") || !strings.Contains(changes[1].Diff, "+  return 'world';") {
+		t.Errorf("unexpected diff of index.js: %s", changes[1].Diff)
+	}
+}
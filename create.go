@@ -91,10 +91,10 @@ func (app *App) prepareFunctionCodeForDeploy(ctx context.Context, opt *DeployOpt
 	return nil
 }
 
-func (app *App) create(ctx context.Context, opt *DeployOption, fn *Function) error {
+func (app *App) create(ctx context.Context, opt *DeployOption, fn *Function) (string, error) {
 	err := app.prepareFunctionCodeForDeploy(ctx, opt, fn)
 	if err != nil {
-		return fmt.Errorf("failed to prepare function code: %w", err)
+		return "", fmt.Errorf("failed to prepare function code: %w", err)
 	}
 	log.Println("[info] creating function", opt.label())
 
@@ -103,7 +103,7 @@ func (app *App) create(ctx context.Context, opt *DeployOption, fn *Function) err
 		fn.Publish = opt.Publish
 		res, err := app.createFunction(ctx, fn)
 		if err != nil {
-			return fmt.Errorf("failed to create function: %w", err)
+			return "", fmt.Errorf("failed to create function: %w", err)
 		}
 		if res.Version != nil {
 			version = *res.Version
@@ -114,11 +114,11 @@ func (app *App) create(ctx context.Context, opt *DeployOption, fn *Function) err
 	}
 
 	if err := app.updateTags(ctx, fn, opt); err != nil {
-		return err
+		return "", err
 	}
 
 	if !opt.Publish {
-		return nil
+		return version, nil
 	}
 
 	log.Printf("[info] creating alias set %s to version %s %s", opt.AliasName, version, opt.label())
@@ -129,11 +129,11 @@ func (app *App) create(ctx context.Context, opt *DeployOption, fn *Function) err
 			Name:            aws.String(opt.AliasName),
 		})
 		if err != nil {
-			return fmt.Errorf("failed to create alias: %w", err)
+			return "", fmt.Errorf("failed to create alias: %w", err)
 		}
 		log.Println("[info] alias created")
 	}
-	return nil
+	return version, nil
 }
 
 func (app *App) createFunction(ctx context.Context, fn *Function) (*lambda.CreateFunctionOutput, error) {
@@ -0,0 +1,181 @@
+package lambroll
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// CurlOption represents options for Curl()
+type CurlOption struct {
+	Path      string   `arg:"" optional:"" help:"path of the request (e.g. /foo?bar=baz)" default:"/"`
+	Method    string   `short:"X" help:"HTTP method" default:"GET"`
+	Headers   []string `name:"header" short:"H" help:"request header (Name: value)"`
+	Data      string   `short:"d" help:"request body. @filename reads the file, @- reads STDIN" default:""`
+	Query     []string `help:"query parameter (key=value)"`
+	Qualifier *string  `help:"alias name of the function URL"`
+	Fail      bool     `default:"false" help:"exit with non-zero status when the response status is not 2xx"`
+}
+
+const functionURLSigningName = "lambda"
+
+// Curl sends a HTTP request to the function URL
+func (app *App) Curl(ctx context.Context, opt *CurlOption) error {
+	fn, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load function: %w", err)
+	}
+	name := aws.ToString(fn.FunctionName)
+	fc, err := app.lambda.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+		FunctionName: &name,
+		Qualifier:    opt.Qualifier,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get function url config of %s: %w", name, err)
+	}
+	log.Printf("[debug] function url %s auth type %s", aws.ToString(fc.FunctionUrl), fc.AuthType)
+
+	req, err := app.newCurlRequest(ctx, aws.ToString(fc.FunctionUrl), fc.AuthType, opt)
+	if err != nil {
+		return err
+	}
+	log.Printf("[info] %s %s", req.Method, req.URL)
+	// no timeout for streaming responses
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	fmt.Fprint(os.Stderr, formatResponseHeader(resp))
+	if err := copyResponseBody(os.Stdout, resp.Body); err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if opt.Fail && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return fmt.Errorf("response status %s", resp.Status)
+	}
+	return nil
+}
+
+func (app *App) newCurlRequest(ctx context.Context, functionURL string, authType types.FunctionUrlAuthType, opt *CurlOption) (*http.Request, error) {
+	u, err := url.Parse(strings.TrimSuffix(functionURL, "/") + "/" + strings.TrimPrefix(opt.Path, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	q := u.Query()
+	for _, kv := range opt.Query {
+		k, v, _ := strings.Cut(kv, "=")
+		q.Add(k, v)
+	}
+	u.RawQuery = q.Encode()
+
+	body, err := readCurlData(opt.Data)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(opt.Method), u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for _, h := range opt.Headers {
+		k, v, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q. header must be Name: value", h)
+		}
+		req.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+
+	if authType == types.FunctionUrlAuthTypeAwsIam {
+		if err := app.signFunctionURLRequest(ctx, req, body); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// signFunctionURLRequest signs the request with SigV4 by the credentials of the app
+func (app *App) signFunctionURLRequest(ctx context.Context, req *http.Request, body []byte) error {
+	creds, err := app.awsConfig.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signer := v4.NewSigner()
+	if err := signer.SignHTTP(ctx, creds, req, payloadHash, functionURLSigningName, app.awsConfig.Region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	log.Println("[debug] signed request with SigV4")
+	return nil
+}
+
+func readCurlData(data string) ([]byte, error) {
+	switch {
+	case data == "@-":
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body from STDIN: %w", err)
+		}
+		return b, nil
+	case strings.HasPrefix(data, "@"):
+		b, err := os.ReadFile(data[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		return b, nil
+	default:
+		return []byte(data), nil
+	}
+}
+
+func formatResponseHeader(resp *http.Response) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", resp.Proto, resp.Status)
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range resp.Header[k] {
+			fmt.Fprintf(&b, "%s: %s\n", k, v)
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// copyResponseBody copies the body to w as it arrives to support streaming responses
+func copyResponseBody(w io.Writer, body io.Reader) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			flush(w)
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
@@ -24,10 +24,12 @@ type DeployOption struct {
 	AliasToLatest bool   `help:"set alias to unpublished $LATEST version" default:"false"`
 	DryRun        bool   `help:"dry run" default:"false"`
 	SkipArchive   bool   `help:"skip to create zip archive. requires Code.S3Bucket and Code.S3Key in function definition" default:"false"`
-	KeepVersions  int    `help:"Number of latest versions to keep. Older versions will be deleted. (Optional value: default 0)." default:"0"`
+	KeepVersions  int    `help:"Number of latest versions to keep. Older versions will be deleted except versions with aliases. (Optional value: default 0)." default:"0"`
 	Ignore        string `help:"ignore fields by jq queries in function.json" default:""`
 	FunctionURL   string `help:"path to function-url definition" default:"" env:"LAMBROLL_FUNCTION_URL"`
 	SkipFunction  bool   `help:"skip to deploy a function. deploy function-url only" default:"false"`
+	Snapshot      string `help:"save a snapshot of tags, function url and permissions to the location (local directory or s3://bucket/prefix) for rollback --restore" default:"" env:"LAMBROLL_SNAPSHOT"`
+	AliasHistory  bool   `help:"record the previous version in the alias description for rollback --strategy=alias-history" default:"false" env:"LAMBROLL_ALIAS_HISTORY"`
 
 	ZipOption
 }
@@ -42,6 +44,9 @@ func (opt DeployOption) label() string {
 type versionAlias struct {
 	Version string
 	Name    string
+
+	// RecordPrevious records the version the alias pointed at in the alias description
+	RecordPrevious bool
 }
 
 // Expand expands ExcludeFile contents to Excludes
@@ -73,7 +78,7 @@ func (opt *DeployOption) String() string {
 
 // Deploy deploys a new lambda function code
 func (app *App) Deploy(ctx context.Context, opt *DeployOption) error {
-	if err := opt.Expand(); err != nil {
+	if err := app.expandZipOption(&opt.ZipOption); err != nil {
 		return err
 	}
 	log.Printf("[debug] %s", opt.String())
@@ -107,12 +112,14 @@ func (app *App) Deploy(ctx context.Context, opt *DeployOption) error {
 		if !errors.As(err, &nfe) {
 			return err
 		}
-		if err := app.create(ctx, opt, fn); err != nil {
+		version, err := app.create(ctx, opt, fn)
+		if err != nil {
 			return err
 		}
 		if err := deployFunctionURL(ctx); err != nil {
 			return err
 		}
+		app.saveSnapshotAfterDeploy(ctx, opt, *fn.FunctionName, version)
 		return nil
 	} else if err := validateUpdateFunction(current.Configuration, current.Code, fn); err != nil {
 		return err
@@ -160,7 +167,7 @@ func (app *App) Deploy(ctx context.Context, opt *DeployOption) error {
 		ImageConfig:       fn.ImageConfig,
 		SnapStart:         fn.SnapStart,
 	}
-	log.Printf("[debug] %s", jsonStr(confIn))
+	log.Printf("[debug] %s", app.jsonStrMasked(confIn))
 
 	var newerVersion string
 	if !opt.DryRun {
@@ -211,19 +218,23 @@ func (app *App) Deploy(ctx context.Context, opt *DeployOption) error {
 		return nil
 	}
 	if opt.Publish || opt.AliasToLatest {
-		err := app.updateAliases(ctx, *fn.FunctionName, versionAlias{newerVersion, opt.AliasName})
+		err := app.updateAliases(ctx, *fn.FunctionName, versionAlias{
+			Version:        newerVersion,
+			Name:           opt.AliasName,
+			RecordPrevious: opt.AliasHistory,
+		})
 		if err != nil {
 			return err
 		}
 	}
-	if opt.KeepVersions > 0 { // Ignore zero-value.
-		return app.deleteVersions(ctx, *fn.FunctionName, opt.KeepVersions)
-	}
-
 	if err := deployFunctionURL(ctx); err != nil {
 		return err
 	}
+	app.saveSnapshotAfterDeploy(ctx, opt, *fn.FunctionName, newerVersion)
 
+	if opt.KeepVersions > 0 { // Ignore zero-value.
+		return app.deleteVersions(ctx, *fn.FunctionName, opt.KeepVersions)
+	}
 	return nil
 }
 
@@ -301,14 +312,47 @@ func (app *App) waitForLastUpdateStatusSuccessful(ctx context.Context, name stri
 	return fmt.Errorf("max retries reached")
 }
 
+// aliasDescriptionToUpdate returns the alias description which records the version the alias pointed at
+// for rollback --strategy=alias-history. It returns nil to keep the description as is.
+func (app *App) aliasDescriptionToUpdate(ctx context.Context, functionName string, v versionAlias) (*string, error) {
+	cur, err := app.lambda.GetAlias(ctx, &lambda.GetAliasInput{
+		FunctionName: aws.String(functionName),
+		Name:         aws.String(v.Name),
+	})
+	if err != nil {
+		var nfe *types.ResourceNotFoundException
+		if errors.As(err, &nfe) {
+			// the alias will be created
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get alias %s: %w", v.Name, err)
+	}
+	if aws.ToString(cur.FunctionVersion) == v.Version {
+		return nil, nil
+	}
+	desc := aliasDescriptionWithPrevious(aws.ToString(cur.Description), aws.ToString(cur.FunctionVersion))
+	if desc == nil {
+		log.Printf("[warn] the description of alias %s is not managed by lambroll. the previous version is not recorded", v.Name)
+	}
+	return desc, nil
+}
+
 func (app *App) updateAliases(ctx context.Context, functionName string, vs ...versionAlias) error {
 	for _, v := range vs {
 		log.Printf("[info] updating alias set %s to version %s", v.Name, v.Version)
-		_, err := app.lambda.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		in := &lambda.UpdateAliasInput{
 			FunctionName:    aws.String(functionName),
 			FunctionVersion: aws.String(v.Version),
 			Name:            aws.String(v.Name),
-		})
+		}
+		if v.RecordPrevious {
+			desc, err := app.aliasDescriptionToUpdate(ctx, functionName, v)
+			if err != nil {
+				return err
+			}
+			in.Description = desc
+		}
+		_, err := app.lambda.UpdateAlias(ctx, in)
 		if err != nil {
 			var nfe *types.ResourceNotFoundException
 			if errors.As(err, &nfe) {
@@ -329,51 +373,3 @@ func (app *App) updateAliases(ctx context.Context, functionName string, vs ...ve
 	}
 	return nil
 }
-
-func (app *App) deleteVersions(ctx context.Context, functionName string, keepVersions int) error {
-	if keepVersions <= 0 {
-		log.Printf("[info] specify --keep-versions")
-		return nil
-	}
-
-	params := &lambda.ListVersionsByFunctionInput{
-		FunctionName: aws.String(functionName),
-	}
-
-	// versions will be set asc order, like 1 to N
-	versions := []types.FunctionConfiguration{}
-	for {
-		res, err := app.lambda.ListVersionsByFunction(ctx, params)
-		if err != nil {
-			return fmt.Errorf("failed to list versions: %w", err)
-		}
-		versions = append(versions, res.Versions...)
-		if res.NextMarker != nil {
-			params.Marker = res.NextMarker
-			continue
-		}
-		break
-	}
-
-	keep := len(versions) - keepVersions
-	for i, v := range versions {
-		if i == 0 {
-			continue
-		}
-		if i >= keep {
-			break
-		}
-
-		log.Printf("[info] deleting function version: %s", *v.Version)
-		_, err := app.lambda.DeleteFunction(ctx, &lambda.DeleteFunctionInput{
-			FunctionName: aws.String(functionName),
-			Qualifier:    v.Version,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to delete version: %w", err)
-		}
-	}
-
-	log.Printf("[info] except %d latest versions are deleted", keepVersions)
-	return nil
-}
@@ -1,13 +1,18 @@
 package lambroll
 
 import (
+	"archive/zip"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/aereal/jsondiff"
@@ -27,81 +32,241 @@ type DiffOption struct {
 	Qualifier   *string `help:"the qualifier to compare"`
 	FunctionURL string  `help:"path to function-url definition" default:"" env:"LAMBROLL_FUNCTION_URL"`
 	Ignore      string  `help:"ignore diff by jq query" default:""`
+	ExitCode    bool    `help:"exit with status 2 when there are differences" default:"false"`
+	Output      string  `help:"output format (text,json,markdown)" default:"text" enum:"text,json,markdown"`
 
 	ZipOption
 }
 
-// Diff prints diff of function.json compared with latest function
-func (app *App) Diff(ctx context.Context, opt *DiffOption) error {
-	if err := opt.Expand(); err != nil {
-		return err
+const (
+	diffCategoryCode        = "code"
+	diffCategoryConfig      = "config"
+	diffCategoryTags        = "tags"
+	diffCategoryURL         = "url"
+	diffCategoryPermissions = "permissions"
+)
+
+// diffChange represents a changed field between the deployed function and the definition.
+type diffChange struct {
+	Path     string `json:"Path"`
+	Category string `json:"Category"`
+	Old      any    `json:"Old"`
+	New      any    `json:"New"`
+	Diff     string `json:"Diff,omitempty"`
+}
+
+type diffReport struct {
+	FunctionName string        `json:"FunctionName"`
+	Changes      []*diffChange `json:"Changes"`
+}
+
+func (r *diffReport) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", r.FunctionName)
+	if len(r.Changes) == 0 {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+	b.WriteString("| Category | Path | Old | New |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, c := range r.Changes {
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s |\n", c.Category, c.Path, markdownValue(c.Old), markdownValue(c.New))
 	}
+	for _, c := range r.Changes {
+		if c.Diff == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details><summary>%s</summary>\n\n```diff\n%s\n```\n\n</details>\n", c.Path, c.Diff)
+	}
+	return b.String()
+}
 
-	newFunc, err := app.loadFunction(app.functionFilePath)
+func markdownValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	bs, _ := json.Marshal(v)
+	s := strings.ReplaceAll(string(bs), "|", "\\|")
+	return "`" + s + "`"
+}
+
+// diffCategories returns unique categories of the changes.
+func diffCategories(changes []*diffChange) []string {
+	var cs []string
+	for _, c := range changes {
+		found := false
+		for _, s := range cs {
+			if s == c.Category {
+				found = true
+				break
+			}
+		}
+		if !found {
+			cs = append(cs, c.Category)
+		}
+	}
+	return cs
+}
+
+func functionChangeCategory(path string) string {
+	switch {
+	case path == "Code" || strings.HasPrefix(path, "Code."):
+		return diffCategoryCode
+	case path == "Tags" || strings.HasPrefix(path, "Tags."):
+		return diffCategoryTags
+	default:
+		return diffCategoryConfig
+	}
+}
+
+// compareValues returns changes between old and new values decoded from JSON.
+// Objects are compared recursively, and other values (including arrays) are compared as a whole.
+func compareValues(path string, old, new any, category func(string) string) []*diffChange {
+	om, oIsMap := old.(map[string]any)
+	nm, nIsMap := new.(map[string]any)
+	if oIsMap && (nIsMap || new == nil) || nIsMap && old == nil {
+		keys := make([]string, 0, len(om)+len(nm))
+		for k := range om {
+			keys = append(keys, k)
+		}
+		for k := range nm {
+			if _, ok := om[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var changes []*diffChange
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			changes = append(changes, compareValues(p, om[k], nm[k], category)...)
+		}
+		return changes
+	}
+	if reflect.DeepEqual(old, new) {
+		return nil
+	}
+	return []*diffChange{{Path: path, Category: category(path), Old: old, New: new}}
+}
+
+func ignoreByQuery(ignore string, values ...any) ([]any, error) {
+	if ignore == "" {
+		return values, nil
+	}
+	p, err := gojq.Parse(ignore)
 	if err != nil {
-		return fmt.Errorf("failed to load function: %w", err)
+		return nil, fmt.Errorf("failed to parse ignore query: %s %w", ignore, err)
 	}
-	fillDefaultValues(newFunc)
-	name := *newFunc.FunctionName
+	q := jsondiff.WithUpdate(p)
+	res := make([]any, 0, len(values))
+	for _, v := range values {
+		m, err := jsondiff.ModifyValue(q, v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply ignore query: %s %w", ignore, err)
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
 
-	var remote *types.FunctionConfiguration
-	var code *types.FunctionCodeLocation
+// deployedFunction represents the deployed function. Configuration is nil if the function does not exist.
+type deployedFunction struct {
+	Configuration *types.FunctionConfiguration
+	Code          *types.FunctionCodeLocation
+	Tags          Tags
+}
 
-	var tags Tags
-	var currentCodeSha256 string
-	var packageType types.PackageType
-	if res, err := app.lambda.GetFunction(ctx, &lambda.GetFunctionInput{
+func (app *App) getDeployedFunction(ctx context.Context, name string, qualifier *string) (*deployedFunction, error) {
+	res, err := app.lambda.GetFunction(ctx, &lambda.GetFunctionInput{
 		FunctionName: &name,
-		Qualifier:    opt.Qualifier,
-	}); err != nil {
+		Qualifier:    qualifier,
+	})
+	if err != nil {
 		var nfe *types.ResourceNotFoundException
 		if errors.As(err, &nfe) {
 			log.Printf("[info] function %s is not found. lambroll deploy will create a new function.", name)
-		} else {
-			return fmt.Errorf("failed to GetFunction %s: %w", name, err)
-		}
-	} else {
-		remote = res.Configuration
-		code = res.Code
-		{
-			log.Println("[debug] list tags Resource", app.functionArn(ctx, name))
-			res, err := app.lambda.ListTags(ctx, &lambda.ListTagsInput{
-				// Tagging operations are permitted on Lambda functions only.
-				// Tags on aliases and versions are not supported.
-				Resource: aws.String(app.functionArn(ctx, name)),
-			})
-			if err != nil {
-				return fmt.Errorf("failed to list tags: %w", err)
-			}
-			tags = res.Tags
+			return &deployedFunction{}, nil
 		}
-		currentCodeSha256 = *res.Configuration.CodeSha256
-		packageType = res.Configuration.PackageType
+		return nil, fmt.Errorf("failed to GetFunction %s: %w", name, err)
 	}
-	remoteFunc := newFunctionFrom(remote, code, tags)
-	fillDefaultValues(remoteFunc)
-
-	opts := []jsondiff.Option{}
-	if ignore := opt.Ignore; ignore != "" {
-		if p, err := gojq.Parse(ignore); err != nil {
-			return fmt.Errorf("failed to parse ignore query: %s %w", ignore, err)
-		} else {
-			opts = append(opts, jsondiff.Ignore(p))
-		}
+	log.Println("[debug] list tags Resource", app.functionArn(ctx, name))
+	tags, err := app.lambda.ListTags(ctx, &lambda.ListTagsInput{
+		// Tagging operations are permitted on Lambda functions only.
+		// Tags on aliases and versions are not supported.
+		Resource: aws.String(app.functionArn(ctx, name)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
 	}
+	return &deployedFunction{
+		Configuration: res.Configuration,
+		Code:          res.Code,
+		Tags:          tags.Tags,
+	}, nil
+}
+
+// diffFunction returns the diff between the deployed function and the function definition.
+// newFunc must be filled default values.
+func (app *App) diffFunction(ctx context.Context, newFunc *Function, deployed *deployedFunction, qualifier *string, src string, ignore string) (string, []*diffChange, error) {
+	name := *newFunc.FunctionName
+	remoteFunc := newFunctionFrom(deployed.Configuration, deployed.Code, deployed.Tags)
+	fillDefaultValues(remoteFunc)
 
 	remoteJSON, _ := marshalAny(remoteFunc)
 	newJSON, _ := marshalAny(newFunc)
-	remoteArn := fullQualifiedFunctionName(app.functionArn(ctx, name), opt.Qualifier)
+	remoteJSON, newJSON = app.masker.maskAnyPair(remoteJSON, newJSON)
+	values, err := ignoreByQuery(ignore, remoteJSON, newJSON)
+	if err != nil {
+		return "", nil, err
+	}
+	remoteJSON, newJSON = values[0], values[1]
+	remoteArn := fullQualifiedFunctionName(app.functionArn(ctx, name), qualifier)
 
-	if diff, err := jsondiff.Diff(
+	ds, err := jsondiff.Diff(
 		&jsondiff.Input{Name: remoteArn, X: remoteJSON},
-		&jsondiff.Input{Name: app.functionFilePath, X: newJSON},
-		opts...,
-	); err != nil {
-		return fmt.Errorf("failed to diff: %w", err)
-	} else if diff != "" {
-		fmt.Print(coloredDiff(diff))
+		&jsondiff.Input{Name: src, X: newJSON},
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to diff: %w", err)
+	}
+	return ds, compareValues("", remoteJSON, newJSON, functionChangeCategory), nil
+}
+
+// Diff prints diff of function.json compared with latest function
+func (app *App) Diff(ctx context.Context, opt *DiffOption) error {
+	if err := app.expandZipOption(&opt.ZipOption); err != nil {
+		return err
+	}
+
+	newFunc, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load function: %w", err)
+	}
+	fillDefaultValues(newFunc)
+	name := *newFunc.FunctionName
+
+	deployed, err := app.getDeployedFunction(ctx, name, opt.Qualifier)
+	if err != nil {
+		return err
+	}
+	remote, code := deployed.Configuration, deployed.Code
+	var currentCodeSha256 string
+	var packageType types.PackageType
+	if remote != nil {
+		currentCodeSha256 = *remote.CodeSha256
+		packageType = remote.PackageType
+	}
+
+	report := &diffReport{FunctionName: name, Changes: []*diffChange{}}
+	var texts []string
+
+	if ds, changes, err := app.diffFunction(ctx, newFunc, deployed, opt.Qualifier, app.functionFilePath, opt.Ignore); err != nil {
+		return err
+	} else {
+		texts = append(texts, coloredDiff(ds))
+		report.Changes = append(report.Changes, changes...)
 	}
 
 	if err := validateUpdateFunction(remote, code, newFunc); err != nil {
@@ -112,10 +277,11 @@ func (app *App) Diff(ctx context.Context, opt *DiffOption) error {
 		if packageType != types.PackageTypeZip {
 			return fmt.Errorf("code-sha256 is only supported for Zip package type")
 		}
-		zipfile, _, err := prepareZipfile(opt.Src, opt.excludes, opt.KeepSymlink)
+		zipfile, info, err := prepareZipfile(opt.Src, opt.excludes, opt.KeepSymlink)
 		if err != nil {
 			return err
 		}
+		defer zipfile.Close()
 		h := sha256.New()
 		if _, err := io.Copy(h, zipfile); err != nil {
 			return err
@@ -123,40 +289,160 @@ func (app *App) Diff(ctx context.Context, opt *DiffOption) error {
 		newCodeSha256 := base64.StdEncoding.EncodeToString(h.Sum(nil))
 		prefix := "CodeSha256: "
 		if ds := diff.Diff(prefix+currentCodeSha256, prefix+newCodeSha256); ds != "" {
-			fmt.Println(color.RedString("---" + app.functionArn(ctx, name)))
-			fmt.Println(color.GreenString("+++" + "--src=" + opt.Src))
-			fmt.Println(coloredDiff(ds))
+			texts = append(texts,
+				color.RedString("---"+app.functionArn(ctx, name))+"\n"+
+					color.GreenString("+++"+"--src="+opt.Src)+"\n"+
+					coloredDiff(ds)+"\n",
+			)
+			report.Changes = append(report.Changes, &diffChange{
+				Path:     "CodeSha256",
+				Category: diffCategoryCode,
+				Old:      currentCodeSha256,
+				New:      newCodeSha256,
+			})
+			if code != nil && code.Location != nil {
+				ts, changes, err := app.diffCodeArchive(*code.Location, zipfile, info.Size())
+				if err != nil {
+					return err
+				}
+				texts = append(texts, ts...)
+				report.Changes = append(report.Changes, changes...)
+			}
 		}
 	}
 
-	if opt.FunctionURL == "" {
-		return nil
+	if opt.FunctionURL != "" {
+		ts, changes, err := app.diffFunctionURL(ctx, name, opt)
+		if err != nil {
+			return err
+		}
+		texts = append(texts, ts...)
+		report.Changes = append(report.Changes, changes...)
 	}
 
-	if err := app.diffFunctionURL(ctx, name, opt); err != nil {
+	if err := printDiffReport(report, texts, opt.Output); err != nil {
 		return err
 	}
+	if opt.ExitCode && len(report.Changes) > 0 {
+		return &ExitCodeError{Code: 2}
+	}
 	return nil
 }
 
-func (app *App) diffFunctionURL(ctx context.Context, name string, opt *DiffOption) error {
-	var remote, local *types.FunctionUrlConfig
+// printDiffReport prints the report in the output format. texts are printed for the text format.
+func printDiffReport(report *diffReport, texts []string, output string) error {
+	switch output {
+	case "json":
+		b, err := marshalJSON(report)
+		if err != nil {
+			return fmt.Errorf("failed to marshal json: %w", err)
+		}
+		fmt.Print(string(b))
+	case "markdown":
+		fmt.Print(report.Markdown())
+	default:
+		for _, t := range texts {
+			if strings.TrimSpace(t) != "" {
+				fmt.Print(t)
+			}
+		}
+	}
+	return nil
+}
 
-	fu, err := app.loadFunctionUrl(opt.FunctionURL, name)
+// diffCodeArchive compares the deployed package at location with the local zip archive file by file.
+func (app *App) diffCodeArchive(location string, local io.ReaderAt, size int64) ([]string, []*diffChange, error) {
+	path, err := downloadCode(location)
 	if err != nil {
-		return fmt.Errorf("failed to load function-url: %w", err)
-	} else {
-		fillDefaultValuesFunctionUrlConfig(fu.Config)
-		local = &types.FunctionUrlConfig{
-			AuthType:   fu.Config.AuthType,
-			Cors:       fu.Config.Cors,
-			InvokeMode: fu.Config.InvokeMode,
+		return nil, nil, err
+	}
+	defer os.Remove(path)
+	remoteZip, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the deployed package: %w", err)
+	}
+	defer remoteZip.Close()
+	localZip, err := zip.NewReader(local, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the local archive: %w", err)
+	}
+	files, err := diffCodeFiles(&remoteZip.Reader, localZip)
+	if err != nil {
+		return nil, nil, err
+	}
+	texts, changes := codeFileChangesReport(files)
+	return texts, changes, nil
+}
+
+// codeFileChangesReport returns texts and changes of the report for the changed files.
+func codeFileChangesReport(files []*codeFileChange) ([]string, []*diffChange) {
+	var texts []string
+	var changes []*diffChange
+	for _, f := range files {
+		c := &diffChange{
+			Path:     "Files/" + f.Name,
+			Category: diffCategoryCode,
+			Diff:     f.Diff,
+		}
+		switch f.Status {
+		case codeFileAdded:
+			c.New = f.NewSize
+			texts = append(texts, color.GreenString(f.String())+"\n")
+		case codeFileRemoved:
+			c.Old = f.OldSize
+			texts = append(texts, color.RedString(f.String())+"\n")
+		default:
+			c.Old, c.New = f.OldSize, f.NewSize
+			texts = append(texts, color.YellowString(f.String())+"\n")
+			if f.Diff != "" {
+				texts = append(texts, coloredDiff(f.Diff))
+			}
 		}
+		changes = append(changes, c)
 	}
-	var qualifier *string
-	if opt.Qualifier != nil {
-		qualifier = opt.Qualifier
-	} else if fu.Config != nil && fu.Config.Qualifier != nil {
+	return texts, changes
+}
+
+func (app *App) diffFunctionURL(ctx context.Context, name string, opt *DiffOption) ([]string, []*diffChange, error) {
+	fu, err := app.loadFunctionUrl(opt.FunctionURL, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load function-url: %w", err)
+	}
+
+	var texts []string
+	var changes []*diffChange
+	if ds, cs, err := app.diffFunctionURLConfig(ctx, name, fu, opt.Qualifier, opt.FunctionURL); err != nil {
+		return nil, nil, err
+	} else if ds != "" {
+		texts = append(texts, coloredDiff(ds))
+		changes = append(changes, cs...)
+	}
+
+	if ds, cs, err := app.diffFunctionURLPermissions(ctx, fu); err != nil {
+		return nil, nil, err
+	} else if ds != "" {
+		texts = append(texts,
+			color.RedString("--- permissions")+"\n"+
+				color.GreenString("+++ permissions")+"\n"+
+				coloredDiff(ds),
+		)
+		changes = append(changes, cs...)
+	}
+
+	return texts, changes, nil
+}
+
+// diffFunctionURLConfig returns the diff between the deployed function url config and the definition
+func (app *App) diffFunctionURLConfig(ctx context.Context, name string, fu *FunctionURL, qualifier *string, src string) (string, []*diffChange, error) {
+	var remote, local *types.FunctionUrlConfig
+
+	fillDefaultValuesFunctionUrlConfig(fu.Config)
+	local = &types.FunctionUrlConfig{
+		AuthType:   fu.Config.AuthType,
+		Cors:       fu.Config.Cors,
+		InvokeMode: fu.Config.InvokeMode,
+	}
+	if qualifier == nil && fu.Config != nil && fu.Config.Qualifier != nil {
 		qualifier = fu.Config.Qualifier
 	}
 	fqName := fullQualifiedFunctionName(name, qualifier)
@@ -170,7 +456,7 @@ func (app *App) diffFunctionURL(ctx context.Context, name string, opt *DiffOptio
 			// empty
 			remote = &types.FunctionUrlConfig{}
 		} else {
-			return fmt.Errorf("failed to get function url config: %w", err)
+			return "", nil, fmt.Errorf("failed to get function url config: %w", err)
 		}
 	} else {
 		log.Println("[debug] FunctionUrlConfig found")
@@ -183,37 +469,47 @@ func (app *App) diffFunctionURL(ctx context.Context, name string, opt *DiffOptio
 	r, _ := toGeneralMap(remote, true)
 	l, _ := toGeneralMap(local, true)
 
-	if diff, err := jsondiff.Diff(
+	ds, err := jsondiff.Diff(
 		&jsondiff.Input{Name: fqName, X: r},
-		&jsondiff.Input{Name: opt.FunctionURL, X: l},
-	); err != nil {
-		return fmt.Errorf("failed to diff: %w", err)
-	} else if diff != "" {
-		fmt.Print(coloredDiff(diff))
+		&jsondiff.Input{Name: src, X: l},
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to diff: %w", err)
 	}
+	changes := compareValues("FunctionURL", r, l, func(string) string { return diffCategoryURL })
+	return ds, changes, nil
+}
 
-	// permissions
+// diffFunctionURLPermissions returns the diff of the function url permissions
+func (app *App) diffFunctionURLPermissions(ctx context.Context, fu *FunctionURL) (string, []*diffChange, error) {
 	adds, removes, err := app.calcFunctionURLPermissionsDiff(ctx, fu)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
+	var changes []*diffChange
 	var addsB []byte
 	for _, in := range adds {
 		b, _ := marshalJSON(in)
 		addsB = append(addsB, b...)
+		v, _ := marshalAny(in)
+		changes = append(changes, &diffChange{
+			Path:     "Permissions." + in.Sid(),
+			Category: diffCategoryPermissions,
+			New:      v,
+		})
 	}
 	var removesB []byte
 	for _, in := range removes {
 		b, _ := marshalJSON(in)
 		removesB = append(removesB, b...)
+		v, _ := marshalAny(in)
+		changes = append(changes, &diffChange{
+			Path:     "Permissions." + in.Sid(),
+			Category: diffCategoryPermissions,
+			Old:      v,
+		})
 	}
-	if ds := diff.Diff(string(removesB), string(addsB)); ds != "" {
-		fmt.Println(color.RedString("--- permissions"))
-		fmt.Println(color.GreenString("+++ permissions"))
-		fmt.Print(coloredDiff(ds))
-	}
-
-	return nil
+	return diff.Diff(string(removesB), string(addsB)), changes, nil
 }
 
 func coloredDiff(src string) string {
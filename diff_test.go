@@ -0,0 +1,56 @@
+package lambroll_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareFunctionValues(t *testing.T) {
+	var old, new any
+	if err := json.Unmarshal([]byte(`{
+		"FunctionName": "hello",
+		"MemorySize": 128,
+		"Layers": ["a"],
+		"Code": {"ImageUri": "repo:v1"},
+		"Environment": {"Variables": {"FOO": "foo", "BAR": "bar"}},
+		"Tags": {"Env": "dev"}
+	}`), &old); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{
+		"FunctionName": "hello",
+		"MemorySize": 256,
+		"Layers": ["a", "b"],
+		"Code": {"ImageUri": "repo:v2"},
+		"Environment": {"Variables": {"FOO": "foo", "BAZ": "baz"}},
+		"Tags": {"Env": "dev", "Owner": "me"},
+		"Timeout": 3
+	}`), &new); err != nil {
+		t.Fatal(err)
+	}
+	expected := []*lambroll.DiffChange{
+		{Path: "Code.ImageUri", Category: "code", Old: "repo:v1", New: "repo:v2"},
+		{Path: "Environment.Variables.BAR", Category: "config", Old: "bar", New: nil},
+		{Path: "Environment.Variables.BAZ", Category: "config", Old: nil, New: "baz"},
+		{Path: "Layers", Category: "config", Old: []any{"a"}, New: []any{"a", "b"}},
+		{Path: "MemorySize", Category: "config", Old: float64(128), New: float64(256)},
+		{Path: "Tags.Owner", Category: "tags", Old: nil, New: "me"},
+		{Path: "Timeout", Category: "config", Old: nil, New: float64(3)},
+	}
+	if diff := cmp.Diff(expected, lambroll.CompareFunctionValues(old, new)); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+
+	// not deployed
+	changes := lambroll.CompareFunctionValues(nil, map[string]any{"FunctionName": "hello"})
+	if len(changes) != 1 || changes[0].Path != "FunctionName" {
+		t.Errorf("unexpected changes for not deployed function: %#v", changes)
+	}
+
+	if changes := lambroll.CompareFunctionValues(old, old); len(changes) != 0 {
+		t.Errorf("expected no changes: %#v", changes)
+	}
+}
@@ -0,0 +1,247 @@
+package lambroll
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// DriftOption represents options for Drift()
+type DriftOption struct {
+	Paths     []string `arg:"" optional:"" help:"function definition files or directories to search recursively" default:"."`
+	Output    string   `help:"output format" default:"table" enum:"table,json,markdown"`
+	Qualifier *string  `help:"the qualifier to compare"`
+	Ignore    string   `help:"ignore diff by jq query" default:""`
+}
+
+const (
+	driftStatusInSync   = "in sync"
+	driftStatusDrifted  = "drifted"
+	driftStatusNotFound = "not found"
+	driftStatusError    = "error"
+)
+
+type driftResult struct {
+	FunctionName   string   `json:"FunctionName"`
+	Definition     string   `json:"Definition"`
+	FunctionURL    string   `json:"FunctionURL,omitempty"`
+	Status         string   `json:"Status"`
+	Changes        []string `json:"Changes,omitempty"`
+	FunctionDiff   string   `json:"FunctionDiff,omitempty"`
+	URLConfigDiff  string   `json:"URLConfigDiff,omitempty"`
+	PermissionDiff string   `json:"PermissionDiff,omitempty"`
+	Error          string   `json:"Error,omitempty"`
+}
+
+func (r *driftResult) drifted() bool {
+	return r.Status == driftStatusDrifted || r.Status == driftStatusNotFound
+}
+
+type driftResults []*driftResult
+
+func (rs driftResults) Table() string {
+	buf := new(strings.Builder)
+	w := tablewriter.NewWriter(buf)
+	w.SetHeader([]string{"Function", "Definition", "Status", "Changes"})
+	for _, r := range rs {
+		changes := strings.Join(r.Changes, ",")
+		if r.Error != "" {
+			changes = r.Error
+		}
+		w.Append([]string{r.FunctionName, r.Definition, r.Status, changes})
+	}
+	w.Render()
+	return buf.String()
+}
+
+func (rs driftResults) JSON() string {
+	b, _ := json.Marshal(rs)
+	var out bytes.Buffer
+	json.Indent(&out, b, "", "  ")
+	return out.String()
+}
+
+func (rs driftResults) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| Function | Definition | Status | Changes |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, r := range rs {
+		changes := strings.Join(r.Changes, ", ")
+		if r.Error != "" {
+			changes = r.Error
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", r.FunctionName, r.Definition, r.Status, changes)
+	}
+	for _, r := range rs {
+		if !r.drifted() {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details><summary>%s (%s)</summary>\n\n```diff\n", r.FunctionName, r.Definition)
+		for _, ds := range []string{r.FunctionDiff, r.URLConfigDiff, r.PermissionDiff} {
+			b.WriteString(ds)
+		}
+		b.WriteString("```\n\n</details>\n")
+	}
+	return b.String()
+}
+
+// findDriftDefinitions returns function definition files in the paths.
+// Directories are searched recursively, and the first file of DefaultFunctionFilenames is used in each directory.
+func findDriftDefinitions(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		st, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if f, err := findFileInDir(p, DefaultFunctionFilenames); err == nil {
+				files = append(files, f)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search function definitions in %s: %w", path, err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func findFileInDir(dir string, names []string) (string, error) {
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+// Drift detects drifts between the function definitions and the deployed functions
+func (app *App) Drift(ctx context.Context, opt *DriftOption) error {
+	files, err := findDriftDefinitions(opt.Paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("function definitions (%s) are not found in %s",
+			strings.Join(DefaultFunctionFilenames, " or "), strings.Join(opt.Paths, ", "))
+	}
+
+	var results driftResults
+	var drifted, failed int
+	for _, file := range files {
+		r := app.detectDrift(ctx, file, opt)
+		switch {
+		case r.Status == driftStatusError:
+			failed++
+		case r.drifted():
+			drifted++
+		}
+		results = append(results, r)
+	}
+
+	switch opt.Output {
+	case "json":
+		fmt.Println(results.JSON())
+	case "markdown":
+		fmt.Print(results.Markdown())
+	default:
+		fmt.Print(results.Table())
+	}
+
+	if failed > 0 {
+		return &ExitCodeError{Code: 1, Err: fmt.Errorf("failed to detect drift of %d functions", failed)}
+	}
+	if drifted > 0 {
+		log.Printf("[warn] %d of %d functions drifted", drifted, len(results))
+		return &ExitCodeError{Code: 2}
+	}
+	log.Printf("[info] all %d functions are in sync", len(results))
+	return nil
+}
+
+func (app *App) detectDrift(ctx context.Context, file string, opt *DriftOption) *driftResult {
+	r := &driftResult{Definition: file}
+	fail := func(err error) *driftResult {
+		log.Printf("[error] %s: %s", file, err)
+		r.Status = driftStatusError
+		r.Error = err.Error()
+		return r
+	}
+
+	fn, err := app.loadFunction(file)
+	if err != nil {
+		return fail(fmt.Errorf("failed to load function: %w", err))
+	}
+	fillDefaultValues(fn)
+	name := *fn.FunctionName
+	r.FunctionName = name
+
+	deployed, err := app.getDeployedFunction(ctx, name, opt.Qualifier)
+	if err != nil {
+		return fail(err)
+	}
+	if deployed.Configuration == nil {
+		r.Status = driftStatusNotFound
+		return r
+	}
+	var changes []*diffChange
+	if r.FunctionDiff, changes, err = app.diffFunction(ctx, fn, deployed, opt.Qualifier, file, opt.Ignore); err != nil {
+		return fail(err)
+	}
+
+	if urlFile, err := findFileInDir(filepath.Dir(file), DefaultFunctionURLFilenames); err == nil {
+		r.FunctionURL = urlFile
+		fu, err := app.loadFunctionUrl(urlFile, name)
+		if err != nil {
+			return fail(fmt.Errorf("failed to load function-url: %w", err))
+		}
+		ds, cs, err := app.diffFunctionURLConfig(ctx, name, fu, opt.Qualifier, urlFile)
+		if err != nil {
+			return fail(err)
+		}
+		r.URLConfigDiff = ds
+		changes = append(changes, cs...)
+		ds, cs, err = app.diffFunctionURLPermissions(ctx, fu)
+		if err != nil {
+			return fail(err)
+		}
+		r.PermissionDiff = ds
+		changes = append(changes, cs...)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fail(err)
+	}
+	r.Changes = diffCategories(changes)
+
+	if len(r.Changes) > 0 {
+		r.Status = driftStatusDrifted
+	} else {
+		r.Status = driftStatusInSync
+	}
+	return r
+}
@@ -0,0 +1,128 @@
+package lambroll_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindDriftDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"a/function.json",
+		"a/function.jsonnet", // function.json is preferred
+		"b/c/function.yaml",
+		"b/function_url.json", // not a function definition
+		".hidden/function.json",
+		"d/function.prod.json", // overlay
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := lambroll.FindDriftDefinitions([]string{dir, filepath.Join(dir, "d/function.prod.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		filepath.Join(dir, "a/function.json"),
+		filepath.Join(dir, "b/c/function.yaml"),
+		filepath.Join(dir, "d/function.prod.json"),
+	}
+	if diff := cmp.Diff(expected, files); diff != "" {
+		t.Errorf("unexpected files (-want +got):\n%s", diff)
+	}
+
+	if _, err := lambroll.FindDriftDefinitions([]string{filepath.Join(dir, "not-found")}); err == nil {
+		t.Error("expected error for not found path")
+	}
+}
+
+func TestDrift(t *testing.T) {
+	const definition = `{"FunctionName":"%s","Handler":"index.handler","Runtime":"nodejs20.x","MemorySize":%d,"Role":"arn:aws:iam::123456789012:role/lambda"}`
+	deployed := map[string]string{
+		"in-sync": fmt.Sprintf(definition, "in-sync", 128),
+		"drifted": fmt.Sprintf(definition, "drifted", 128),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/2017-03-31/tags/"):
+			fmt.Fprint(w, `{"Tags":{}}`)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/2015-03-31/functions/"):
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/2015-03-31/functions/"), "/")
+			conf, ok := deployed[name]
+			if !ok {
+				w.Header().Set("X-Amzn-Errortype", "AccessDeniedException")
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"Type":"User","message":"not authorized to perform: lambda:GetFunction"}`)
+				return
+			}
+			fmt.Fprintf(w, `{"Configuration":%s}`, conf)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	for name, memory := range map[string]int{"in-sync": 128, "drifted": 256, "failing": 128} {
+		p := filepath.Join(dir, name, "function.json")
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(fmt.Sprintf(definition, name, memory)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := newTestApp(t, ts)
+	app.CallerIdentity().Resolver = func(_ context.Context) (*sts.GetCallerIdentityOutput, error) {
+		return &sts.GetCallerIdentityOutput{
+			Account: aws.String("123456789012"),
+			Arn:     aws.String("arn:aws:iam::123456789012:user/test-user"),
+			UserId:  aws.String("AIDXXXXXXXXXXXXXXXXXX"),
+		}, nil
+	}
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		paths []string
+		code  int
+	}{
+		{name: "in sync", paths: []string{filepath.Join(dir, "in-sync")}, code: 0},
+		{name: "drifted", paths: []string{filepath.Join(dir, "in-sync"), filepath.Join(dir, "drifted")}, code: 2},
+		{name: "failing", paths: []string{dir}, code: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := app.Drift(ctx, &lambroll.DriftOption{Paths: tt.paths, Output: "json"})
+			if tt.code == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			var ee *lambroll.ExitCodeError
+			if !errors.As(err, &ee) || ee.Code != tt.code {
+				t.Errorf("unexpected error: %v, expected exit code %d", err, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,574 @@
+package lambroll
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// ExportOption represents options for Export()
+type ExportOption struct {
+	Format          string `help:"export format (sam,cfn,terraform)" default:"sam" enum:"sam,cfn,terraform"`
+	FunctionURL     string `help:"path to function-url definition" default:"" env:"LAMBROLL_FUNCTION_URL"`
+	ResourceName    string `help:"resource name (logical ID) of the function. default: derived from the function name" default:""`
+	S3Bucket        string `name:"s3-bucket" help:"S3 bucket of the function code (default: Code.S3Bucket in the definition)" default:""`
+	S3Key           string `name:"s3-key" help:"S3 key of the function code (default: Code.S3Key in the definition)" default:""`
+	S3ObjectVersion string `name:"s3-object-version" help:"S3 object version of the function code" default:""`
+}
+
+// Export exports the function definition as SAM/CloudFormation template or Terraform HCL
+func (app *App) Export(ctx context.Context, opt *ExportOption) error {
+	fn, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load function: %w", err)
+	}
+	if err := exportFunctionCode(fn, opt); err != nil {
+		return err
+	}
+	// secret values are never exported. they are replaced with references.
+	var secrets map[string]string
+	if fn.Environment != nil {
+		secrets = app.masker.secretVariables(fn.Environment.Variables)
+	}
+	var fu *FunctionURL
+	if opt.FunctionURL != "" {
+		fu, err = app.loadFunctionUrl(opt.FunctionURL, *fn.FunctionName)
+		if err != nil {
+			return fmt.Errorf("failed to load function-url: %w", err)
+		}
+		fillDefaultValuesFunctionUrlConfig(fu.Config)
+	}
+
+	var b []byte
+	switch opt.Format {
+	case "sam", "cfn":
+		name := opt.ResourceName
+		if name == "" {
+			name = cfnLogicalID(*fn.FunctionName)
+		}
+		tmpl, err := exportCFnTemplate(fn, fu, name, opt.Format == "sam", secrets)
+		if err != nil {
+			return err
+		}
+		if b, err = marshalJSON(tmpl); err != nil {
+			return fmt.Errorf("failed to marshal template: %w", err)
+		}
+		if b, err = jsonToYAML(b); err != nil {
+			return err
+		}
+	case "terraform":
+		name := opt.ResourceName
+		if name == "" {
+			name = terraformName(*fn.FunctionName)
+		}
+		b = []byte(exportTerraform(fn, fu, name, secrets))
+	default:
+		return fmt.Errorf("unknown export format: %s", opt.Format)
+	}
+	log.Printf("[info] exporting function %s as %s", *fn.FunctionName, opt.Format)
+	if _, err := os.Stdout.Write(b); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	return nil
+}
+
+// exportFunctionCode sets S3 location of the function code by options
+func exportFunctionCode(fn *Function, opt *ExportOption) error {
+	if fn.Code == nil {
+		fn.Code = &types.FunctionCode{}
+	}
+	fn.Code.ZipFile = nil
+	if opt.S3Bucket != "" {
+		fn.Code.S3Bucket = aws.String(opt.S3Bucket)
+	}
+	if opt.S3Key != "" {
+		fn.Code.S3Key = aws.String(opt.S3Key)
+	}
+	if opt.S3ObjectVersion != "" {
+		fn.Code.S3ObjectVersion = aws.String(opt.S3ObjectVersion)
+	}
+	if fn.PackageType == types.PackageTypeImage {
+		if fn.Code.ImageUri == nil {
+			return fmt.Errorf("PackageType=Image requires Code.ImageUri in function definition")
+		}
+		return nil
+	}
+	if fn.Code.S3Bucket == nil || fn.Code.S3Key == nil {
+		return fmt.Errorf("the function code must be referenced by S3 location. specify Code.S3Bucket and Code.S3Key in function definition or --s3-bucket and --s3-key")
+	}
+	return nil
+}
+
+var nonAlnum = regexp.MustCompile(`[^0-9A-Za-z]+`)
+
+// cfnLogicalID converts the function name to a logical ID (e.g. hello-world => HelloWorld)
+func cfnLogicalID(name string) string {
+	var b strings.Builder
+	for _, p := range nonAlnum.Split(name, -1) {
+		if p == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+	}
+	if b.Len() == 0 {
+		return "Function"
+	}
+	return b.String()
+}
+
+// terraformName converts the function name to a terraform resource name (e.g. hello-world => hello_world)
+func terraformName(name string) string {
+	s := strings.Trim(nonAlnum.ReplaceAllString(name, "_"), "_")
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		s = "function_" + s
+	}
+	return s
+}
+
+// exportCFnTemplate exports the function as a CloudFormation (or SAM) template.
+// secrets are the environment variables which have secret values (see masker.secretVariables).
+// They are replaced with the dynamic references, or NoEcho parameters when no reference is available.
+func exportCFnTemplate(fn *Function, fu *FunctionURL, name string, sam bool, secrets map[string]string) (map[string]any, error) {
+	x, err := marshalAny(fn)
+	if err != nil {
+		return nil, err
+	}
+	props, _ := x.(map[string]any)
+	if props == nil {
+		props = map[string]any{}
+	}
+	if v, ok := props["KMSKeyArn"]; ok {
+		props["KmsKeyArn"] = v
+		delete(props, "KMSKeyArn")
+	}
+	delete(props, "Publish")
+	delete(props, "Code")
+	delete(props, "Tags")
+
+	resources := map[string]any{}
+	tmpl := map[string]any{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Resources":                resources,
+	}
+	if env, ok := props["Environment"].(map[string]any); ok && len(secrets) > 0 {
+		vars, _ := env["Variables"].(map[string]any)
+		params := map[string]any{}
+		for k, ref := range secrets {
+			if ref != "" {
+				vars[k] = ref
+				continue
+			}
+			param := name + cfnLogicalID(k)
+			log.Printf("[warn] the value of environment variable %s is exported as the NoEcho parameter %s", k, param)
+			params[param] = map[string]any{
+				"Type":        "String",
+				"NoEcho":      true,
+				"Description": fmt.Sprintf("environment variable %s of %s", k, name),
+			}
+			vars[k] = map[string]any{"Ref": param}
+		}
+		if len(params) > 0 {
+			tmpl["Parameters"] = params
+		}
+	}
+	functionArn := map[string]any{"Fn::GetAtt": []string{name, "Arn"}}
+	code := fn.Code
+
+	if sam {
+		tmpl["Transform"] = "AWS::Serverless-2016-10-31"
+		if fn.PackageType == types.PackageTypeImage {
+			props["ImageUri"] = aws.ToString(code.ImageUri)
+		} else {
+			codeURI := map[string]any{
+				"Bucket": aws.ToString(code.S3Bucket),
+				"Key":    aws.ToString(code.S3Key),
+			}
+			if code.S3ObjectVersion != nil {
+				codeURI["Version"] = *code.S3ObjectVersion
+			}
+			props["CodeUri"] = codeURI
+		}
+		if len(fn.Tags) > 0 {
+			props["Tags"] = fn.Tags
+		}
+		if tc := fn.TracingConfig; tc != nil {
+			props["Tracing"] = string(tc.Mode)
+			delete(props, "TracingConfig")
+		}
+		if dlc := fn.DeadLetterConfig; dlc != nil && dlc.TargetArn != nil {
+			typ := "SNS"
+			if strings.Contains(*dlc.TargetArn, ":sqs:") {
+				typ = "SQS"
+			}
+			props["DeadLetterQueue"] = map[string]any{
+				"Type":      typ,
+				"TargetArn": *dlc.TargetArn,
+			}
+			delete(props, "DeadLetterConfig")
+		}
+		if fu != nil {
+			urlConfig := map[string]any{
+				"AuthType":   string(fu.Config.AuthType),
+				"InvokeMode": string(fu.Config.InvokeMode),
+			}
+			if fu.Config.Cors != nil {
+				urlConfig["Cors"] = fu.Config.Cors
+			}
+			props["FunctionUrlConfig"] = urlConfig
+		}
+		resources[name] = map[string]any{
+			"Type":       "AWS::Serverless::Function",
+			"Properties": props,
+		}
+	} else {
+		c := map[string]any{}
+		if fn.PackageType == types.PackageTypeImage {
+			c["ImageUri"] = aws.ToString(code.ImageUri)
+		} else {
+			c["S3Bucket"] = aws.ToString(code.S3Bucket)
+			c["S3Key"] = aws.ToString(code.S3Key)
+			if code.S3ObjectVersion != nil {
+				c["S3ObjectVersion"] = *code.S3ObjectVersion
+			}
+		}
+		props["Code"] = c
+		if len(fn.Tags) > 0 {
+			props["Tags"] = cfnTags(fn.Tags)
+		}
+		resources[name] = map[string]any{
+			"Type":       "AWS::Lambda::Function",
+			"Properties": props,
+		}
+		if fu != nil {
+			urlProps := map[string]any{
+				"TargetFunctionArn": functionArn,
+				"AuthType":          string(fu.Config.AuthType),
+				"InvokeMode":        string(fu.Config.InvokeMode),
+			}
+			if fu.Config.Qualifier != nil {
+				urlProps["Qualifier"] = *fu.Config.Qualifier
+			}
+			if fu.Config.Cors != nil {
+				urlProps["Cors"] = fu.Config.Cors
+			}
+			resources[name+"Url"] = map[string]any{
+				"Type":       "AWS::Lambda::Url",
+				"Properties": urlProps,
+			}
+		}
+	}
+
+	if fu != nil {
+		var target any = functionArn
+		if q := fu.Config.Qualifier; q != nil {
+			target = map[string]any{"Fn::Join": []any{":", []any{functionArn, *q}}}
+		}
+		for i, p := range fu.Permissions {
+			if sam && fu.Config.AuthType == types.FunctionUrlAuthTypeNone && aws.ToString(p.Principal) == "*" {
+				// SAM creates the public permission for AuthType NONE
+				continue
+			}
+			perm := map[string]any{
+				"Action":              "lambda:InvokeFunctionUrl",
+				"FunctionName":        target,
+				"FunctionUrlAuthType": string(fu.Config.AuthType),
+				"Principal":           aws.ToString(p.Principal),
+			}
+			if p.PrincipalOrgID != nil {
+				perm["PrincipalOrgID"] = *p.PrincipalOrgID
+			}
+			if p.SourceArn != nil {
+				perm["SourceArn"] = *p.SourceArn
+			}
+			if p.SourceAccount != nil {
+				perm["SourceAccount"] = *p.SourceAccount
+			}
+			resources[fmt.Sprintf("%sUrlPermission%d", name, i+1)] = map[string]any{
+				"Type":       "AWS::Lambda::Permission",
+				"Properties": perm,
+			}
+		}
+	}
+	return tmpl, nil
+}
+
+func cfnTags(tags Tags) []map[string]string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ts := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		ts = append(ts, map[string]string{"Key": k, "Value": tags[k]})
+	}
+	return ts
+}
+
+// hclBlock represents a block of Terraform HCL
+type hclBlock struct {
+	typ    string
+	labels []string
+	attrs  [][2]string // name, encoded value
+	blocks []*hclBlock
+}
+
+func (b *hclBlock) attr(name string, v any) {
+	if s := hclValue(v); s != "" {
+		b.attrs = append(b.attrs, [2]string{name, s})
+	}
+}
+
+func (b *hclBlock) block(typ string) *hclBlock {
+	c := &hclBlock{typ: typ}
+	b.blocks = append(b.blocks, c)
+	return c
+}
+
+func (b *hclBlock) write(w *strings.Builder, indent string) {
+	w.WriteString(indent + b.typ)
+	for _, l := range b.labels {
+		w.WriteString(" " + strconv.Quote(l))
+	}
+	w.WriteString(" {\n")
+	width := 0
+	for _, a := range b.attrs {
+		if len(a[0]) > width {
+			width = len(a[0])
+		}
+	}
+	for _, a := range b.attrs {
+		fmt.Fprintf(w, "%s  %-*s = %s\n", indent, width, a[0], strings.ReplaceAll(a[1], "\n", "\n"+indent+"  "))
+	}
+	for _, c := range b.blocks {
+		if len(b.attrs) > 0 || c != b.blocks[0] {
+			w.WriteString("\n")
+		}
+		c.write(w, indent+"  ")
+	}
+	w.WriteString(indent + "}\n")
+}
+
+func hclString(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+// hclValue encodes v as HCL expression. returns empty string for empty values.
+func hclValue(v any) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case *string:
+		if vv == nil {
+			return ""
+		}
+		return hclString(*vv)
+	case string:
+		if vv == "" {
+			return ""
+		}
+		return hclString(vv)
+	case *int32:
+		if vv == nil {
+			return ""
+		}
+		return strconv.FormatInt(int64(*vv), 10)
+	case *bool:
+		if vv == nil {
+			return ""
+		}
+		return strconv.FormatBool(*vv)
+	case bool:
+		return strconv.FormatBool(vv)
+	case hclRaw:
+		return string(vv)
+	case []string:
+		if vv == nil {
+			return ""
+		}
+		items := make([]string, 0, len(vv))
+		for _, s := range vv {
+			items = append(items, hclString(s))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]string:
+		m := make(map[string]any, len(vv))
+		for k, s := range vv {
+			m[k] = s
+		}
+		return hclValue(m)
+	case map[string]any:
+		if len(vv) == 0 {
+			return ""
+		}
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, k := range keys {
+			s := hclValue(vv[k])
+			if s == "" {
+				s = `""`
+			}
+			fmt.Fprintf(&b, "  %s = %s\n", hclString(k), s)
+		}
+		b.WriteString("}")
+		return b.String()
+	default:
+		return hclString(fmt.Sprint(vv))
+	}
+}
+
+// hclRaw is a raw HCL expression (e.g. reference to other resources)
+type hclRaw string
+
+// exportTerraform exports the function as Terraform HCL.
+// secrets are the environment variables which have secret values. They are replaced with sensitive variables.
+func exportTerraform(fn *Function, fu *FunctionURL, name string, secrets map[string]string) string {
+	var blocks, variables []*hclBlock
+	f := &hclBlock{typ: "resource", labels: []string{"aws_lambda_function", name}}
+	blocks = append(blocks, f)
+
+	f.attr("function_name", fn.FunctionName)
+	f.attr("description", fn.Description)
+	f.attr("role", fn.Role)
+	if fn.PackageType == types.PackageTypeImage {
+		f.attr("package_type", string(fn.PackageType))
+		f.attr("image_uri", fn.Code.ImageUri)
+	} else {
+		f.attr("handler", fn.Handler)
+		f.attr("runtime", string(fn.Runtime))
+		f.attr("s3_bucket", fn.Code.S3Bucket)
+		f.attr("s3_key", fn.Code.S3Key)
+		f.attr("s3_object_version", fn.Code.S3ObjectVersion)
+	}
+	if len(fn.Architectures) > 0 {
+		archs := make([]string, 0, len(fn.Architectures))
+		for _, a := range fn.Architectures {
+			archs = append(archs, string(a))
+		}
+		f.attr("architectures", archs)
+	}
+	f.attr("memory_size", fn.MemorySize)
+	f.attr("timeout", fn.Timeout)
+	if len(fn.Layers) > 0 {
+		f.attr("layers", fn.Layers)
+	}
+	f.attr("kms_key_arn", fn.KMSKeyArn)
+	f.attr("code_signing_config_arn", fn.CodeSigningConfigArn)
+	if len(fn.Tags) > 0 {
+		f.attr("tags", map[string]string(fn.Tags))
+	}
+
+	if e := fn.Environment; e != nil && len(e.Variables) > 0 {
+		vars := make(map[string]any, len(e.Variables))
+		for k, v := range e.Variables {
+			vars[k] = v
+		}
+		keys := make([]string, 0, len(secrets))
+		for k := range secrets {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			vn := terraformName(name + "_" + strings.ToLower(k))
+			log.Printf("[warn] the value of environment variable %s is exported as the sensitive variable %s", k, vn)
+			v := &hclBlock{typ: "variable", labels: []string{vn}}
+			v.attr("type", hclRaw("string"))
+			v.attr("sensitive", true)
+			variables = append(variables, v)
+			vars[k] = hclRaw("var." + vn)
+		}
+		f.block("environment").attr("variables", vars)
+	}
+	if es := fn.EphemeralStorage; es != nil {
+		f.block("ephemeral_storage").attr("size", es.Size)
+	}
+	if tc := fn.TracingConfig; tc != nil && tc.Mode != "" {
+		f.block("tracing_config").attr("mode", string(tc.Mode))
+	}
+	if lc := fn.LoggingConfig; lc != nil {
+		b := f.block("logging_config")
+		b.attr("log_format", string(lc.LogFormat))
+		b.attr("log_group", lc.LogGroup)
+		b.attr("application_log_level", string(lc.ApplicationLogLevel))
+		b.attr("system_log_level", string(lc.SystemLogLevel))
+	}
+	if vc := fn.VpcConfig; vc != nil {
+		b := f.block("vpc_config")
+		b.attr("subnet_ids", vc.SubnetIds)
+		b.attr("security_group_ids", vc.SecurityGroupIds)
+		b.attr("ipv6_allowed_for_dual_stack", vc.Ipv6AllowedForDualStack)
+	}
+	if dlc := fn.DeadLetterConfig; dlc != nil {
+		f.block("dead_letter_config").attr("target_arn", dlc.TargetArn)
+	}
+	for _, fsc := range fn.FileSystemConfigs {
+		b := f.block("file_system_config")
+		b.attr("arn", fsc.Arn)
+		b.attr("local_mount_path", fsc.LocalMountPath)
+	}
+	if ic := fn.ImageConfig; ic != nil {
+		b := f.block("image_config")
+		b.attr("command", ic.Command)
+		b.attr("entry_point", ic.EntryPoint)
+		b.attr("working_directory", ic.WorkingDirectory)
+	}
+	if ss := fn.SnapStart; ss != nil && ss.ApplyOn != "" && ss.ApplyOn != types.SnapStartApplyOnNone {
+		f.block("snap_start").attr("apply_on", string(ss.ApplyOn))
+	}
+
+	if fu != nil {
+		functionName := hclRaw(fmt.Sprintf("aws_lambda_function.%s.function_name", name))
+		u := &hclBlock{typ: "resource", labels: []string{"aws_lambda_function_url", name}}
+		blocks = append(blocks, u)
+		u.attr("function_name", functionName)
+		u.attr("qualifier", fu.Config.Qualifier)
+		u.attr("authorization_type", string(fu.Config.AuthType))
+		u.attr("invoke_mode", string(fu.Config.InvokeMode))
+		if c := fu.Config.Cors; c != nil {
+			b := u.block("cors")
+			b.attr("allow_credentials", c.AllowCredentials)
+			b.attr("allow_headers", c.AllowHeaders)
+			b.attr("allow_methods", c.AllowMethods)
+			b.attr("allow_origins", c.AllowOrigins)
+			b.attr("expose_headers", c.ExposeHeaders)
+			b.attr("max_age", c.MaxAge)
+		}
+		for i, p := range fu.Permissions {
+			pb := &hclBlock{typ: "resource", labels: []string{"aws_lambda_permission", fmt.Sprintf("%s_url_%d", name, i+1)}}
+			blocks = append(blocks, pb)
+			pb.attr("statement_id", p.Sid())
+			pb.attr("action", "lambda:InvokeFunctionUrl")
+			pb.attr("function_name", functionName)
+			pb.attr("qualifier", fu.Config.Qualifier)
+			pb.attr("function_url_auth_type", string(fu.Config.AuthType))
+			pb.attr("principal", p.Principal)
+			pb.attr("principal_org_id", p.PrincipalOrgID)
+			pb.attr("source_arn", p.SourceArn)
+			pb.attr("source_account", p.SourceAccount)
+		}
+	}
+
+	var w strings.Builder
+	for i, b := range append(variables, blocks...) {
+		if i > 0 {
+			w.WriteString("\n")
+		}
+		b.write(&w, "")
+	}
+	return w.String()
+}
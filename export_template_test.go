@@ -0,0 +1,177 @@
+package lambroll_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func testExportFunction() (*lambroll.Function, *lambroll.FunctionURL) {
+	fn := &lambroll.Function{
+		FunctionName: aws.String("hello-world"),
+		Handler:      aws.String("index.handler"),
+		Runtime:      types.RuntimeNodejs20x,
+		Role:         aws.String("arn:aws:iam::123456789012:role/hello"),
+		MemorySize:   aws.Int32(256),
+		Environment: &types.Environment{
+			Variables: map[string]string{"FOO": "${foo}"},
+		},
+		TracingConfig: &types.TracingConfig{Mode: types.TracingModeActive},
+		Code: &types.FunctionCode{
+			S3Bucket: aws.String("my-bucket"),
+			S3Key:    aws.String("hello.zip"),
+		},
+		Tags: map[string]string{"Env": "dev"},
+	}
+	fu := &lambroll.FunctionURL{
+		Config: &lambroll.FunctionURLConfig{
+			AuthType:   types.FunctionUrlAuthTypeAwsIam,
+			InvokeMode: types.InvokeModeBuffered,
+		},
+		Permissions: lambroll.FunctionURLPermissions{
+			{
+				AddPermissionInput: lambda.AddPermissionInput{
+					Principal:   aws.String("123456789012"),
+					StatementId: aws.String("lambroll-test"),
+				},
+			},
+		},
+	}
+	return fn, fu
+}
+
+func TestExportCFnTemplate(t *testing.T) {
+	fn, fu := testExportFunction()
+	tmpl, err := lambroll.ExportCFnTemplate(fn, fu, "HelloWorld", true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl["Transform"] != "AWS::Serverless-2016-10-31" {
+		t.Errorf("unexpected transform: %v", tmpl["Transform"])
+	}
+	resources := tmpl["Resources"].(map[string]any)
+	f := resources["HelloWorld"].(map[string]any)
+	if f["Type"] != "AWS::Serverless::Function" {
+		t.Errorf("unexpected type: %v", f["Type"])
+	}
+	props := f["Properties"].(map[string]any)
+	if diff := cmp.Diff(map[string]any{"Bucket": "my-bucket", "Key": "hello.zip"}, props["CodeUri"]); diff != "" {
+		t.Errorf("unexpected CodeUri %s", diff)
+	}
+	if props["Tracing"] != "Active" {
+		t.Errorf("unexpected Tracing: %v", props["Tracing"])
+	}
+	if _, ok := resources["HelloWorldUrlPermission1"]; !ok {
+		t.Errorf("permission resource not found")
+	}
+
+	tmpl, err = lambroll.ExportCFnTemplate(fn, fu, "HelloWorld", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources = tmpl["Resources"].(map[string]any)
+	props = resources["HelloWorld"].(map[string]any)["Properties"].(map[string]any)
+	if diff := cmp.Diff([]map[string]string{{"Key": "Env", "Value": "dev"}}, props["Tags"]); diff != "" {
+		t.Errorf("unexpected Tags %s", diff)
+	}
+	if _, ok := resources["HelloWorldUrl"]; !ok {
+		t.Errorf("url resource not found")
+	}
+}
+
+var testExportTerraformExpected = `resource "aws_lambda_function" "hello_world" {
+  function_name = "hello-world"
+  role          = "arn:aws:iam::123456789012:role/hello"
+  handler       = "index.handler"
+  runtime       = "nodejs20.x"
+  s3_bucket     = "my-bucket"
+  s3_key        = "hello.zip"
+  memory_size   = 256
+  tags          = {
+    "Env" = "dev"
+  }
+
+  environment {
+    variables = {
+      "FOO" = "$${foo}"
+    }
+  }
+
+  tracing_config {
+    mode = "Active"
+  }
+}
+
+resource "aws_lambda_function_url" "hello_world" {
+  function_name      = aws_lambda_function.hello_world.function_name
+  authorization_type = "AWS_IAM"
+  invoke_mode        = "BUFFERED"
+}
+
+resource "aws_lambda_permission" "hello_world_url_1" {
+  statement_id           = "lambroll-test"
+  action                 = "lambda:InvokeFunctionUrl"
+  function_name          = aws_lambda_function.hello_world.function_name
+  function_url_auth_type = "AWS_IAM"
+  principal              = "123456789012"
+}
+`
+
+func TestExportTerraform(t *testing.T) {
+	fn, fu := testExportFunction()
+	got := lambroll.ExportTerraform(fn, fu, "hello_world", nil)
+	if diff := cmp.Diff(testExportTerraformExpected, got); diff != "" {
+		t.Errorf("(-expected +got)\n%s", diff)
+	}
+}
+
+func TestExportSecrets(t *testing.T) {
+	m := lambroll.NewMasker([]string{"*_TOKEN"})
+	m.Add("pa55w0rd")
+	fn, _ := testExportFunction()
+	fn.Environment.Variables = map[string]string{
+		"API_TOKEN":   "t0ken",
+		"DB_PASSWORD": "pa55w0rd",
+		"DB_HOST":     "db.example.com",
+	}
+	secrets := m.SecretVariables(fn.Environment.Variables)
+	secrets["DB_PASSWORD"] = "{{resolve:secretsmanager:db:SecretString:password}}"
+
+	tmpl, err := lambroll.ExportCFnTemplate(fn, nil, "HelloWorld", false, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := tmpl["Resources"].(map[string]any)["HelloWorld"].(map[string]any)["Properties"].(map[string]any)
+	expected := map[string]any{
+		"API_TOKEN":   map[string]any{"Ref": "HelloWorldAPITOKEN"},
+		"DB_PASSWORD": "{{resolve:secretsmanager:db:SecretString:password}}",
+		"DB_HOST":     "db.example.com",
+	}
+	if diff := cmp.Diff(expected, props["Environment"].(map[string]any)["Variables"]); diff != "" {
+		t.Errorf("unexpected variables (-expected +got)\n%s", diff)
+	}
+	param := tmpl["Parameters"].(map[string]any)["HelloWorldAPITOKEN"].(map[string]any)
+	if param["NoEcho"] != true {
+		t.Errorf("parameter for the secret must be NoEcho: %v", param)
+	}
+
+	hcl := lambroll.ExportTerraform(fn, nil, "hello_world", secrets)
+	for _, s := range []string{
+		`variable "hello_world_api_token" {`,
+		`variable "hello_world_db_password" {`,
+		`"API_TOKEN" = var.hello_world_api_token`,
+		`"DB_HOST" = "db.example.com"`,
+	} {
+		if !strings.Contains(hcl, s) {
+			t.Errorf("exported HCL must contain %s\n%s", s, hcl)
+		}
+	}
+	if strings.Contains(hcl, "t0ken") || strings.Contains(hcl, "pa55w0rd") {
+		t.Errorf("exported HCL must not contain secret values\n%s", hcl)
+	}
+}
@@ -1,24 +1,204 @@
 package lambroll
 
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
 var (
-	CreateZipArchive  = createZipArchive
-	ExpandExcludeFile = expandExcludeFile
-	LoadZipArchive    = loadZipArchive
-	MergeTags         = mergeTags
-	FillDefaultValues = fillDefaultValues
-	JSONStr           = jsonStr
-	MarshalJSON       = marshalJSON
-	NewFunctionFrom   = newFunctionFrom
-	NewCallerIdentity = newCallerIdentity
+	CreateZipArchive      = createZipArchive
+	ExpandExcludeFile     = expandExcludeFile
+	LoadZipArchive        = loadZipArchive
+	MergeTags             = mergeTags
+	FillDefaultValues     = fillDefaultValues
+	JSONStr               = jsonStr
+	MarshalJSON           = marshalJSON
+	NewFunctionFrom       = newFunctionFrom
+	NewCallerIdentity     = newCallerIdentity
+	NewSecretsManager     = newSecretsManager
+	NewMasker             = newMasker
+	JSONToYAML            = jsonToYAML
+	YAMLToJSON            = yamlToJSON
+	MergeDefinition       = mergeDefinition
+	OverlayFilenames      = overlayFilenames
+	ExportCFnTemplate     = exportCFnTemplate
+	ExportTerraform       = exportTerraform
+	ReadCFnTemplate       = readCFnTemplate
+	NewCFnResolver        = newCFnResolver
+	ImportFunction        = importFunction
+	FindDriftDefinitions  = findDriftDefinitions
+	DiffCodeFiles         = diffCodeFiles
+	NewStatusOutput       = newStatusOutput
+	NewStatusAliases      = newStatusAliases
+	ParseReportLine       = parseReportLine
+	SnapshotPath          = snapshotPath
+	SelectPreviousVersion = selectPreviousVersion
+	AliasDescription      = aliasDescriptionWithPrevious
+	DiffSnapshot          = diffSnapshot
+	CompareFunctionValues = func(old, new any) []*DiffChange { return compareValues("", old, new, functionChangeCategory) }
 )
 
 type VersionsOutput = versionsOutput
 type VersionsOutputs = versionsOutputs
+type DiffChange = diffChange
+type FunctionSnapshot = functionSnapshot
+type FunctionPermission = functionPermission
+
+func (app *App) DiffVersionsChanges(ctx context.Context, name string, opt *VersionsDiffOption) ([]*DiffChange, error) {
+	report, _, err := app.diffVersions(ctx, name, opt)
+	if err != nil {
+		return nil, err
+	}
+	return report.Changes, nil
+}
+
+func (app *App) SaveSnapshot(ctx context.Context, location string, snap *FunctionSnapshot) error {
+	return app.saveSnapshot(ctx, location, snap)
+}
+
+func (app *App) PruneVersionsWithOutput(ctx context.Context, opt *VersionsPruneOption, w io.Writer) error {
+	return app.pruneVersionsByOption(ctx, opt, w)
+}
+
+// SetRetryPolicy sets the delays and the max count of retryPolicy, and returns the function to restore it
+func SetRetryPolicy(minDelay, maxDelay time.Duration, maxCount int) func() {
+	orig := retryPolicy
+	retryPolicy.MinDelay, retryPolicy.MaxDelay, retryPolicy.MaxCount = minDelay, maxDelay, maxCount
+	return func() { retryPolicy = orig }
+}
+
+// NewRuntimeAPI returns the runtime api and its address
+func NewRuntimeAPI() (*runtimeAPI, string, error) {
+	api, err := newRuntimeAPI()
+	if err != nil {
+		return nil, "", err
+	}
+	return api, api.Addr(), nil
+}
+
+// UpdateAlias updates the alias to the version
+func (app *App) UpdateAlias(ctx context.Context, name, alias, version string, recordPrevious bool) error {
+	return app.updateAliases(ctx, name, versionAlias{Version: version, Name: alias, RecordPrevious: recordPrevious})
+}
+
+func (app *App) RestoreSnapshot(ctx context.Context, snap *FunctionSnapshot, alias string, dryRun bool) error {
+	return app.restoreSnapshot(ctx, snap, alias, dryRun)
+}
+
+func (app *App) LoadSnapshot(ctx context.Context, location, name, version string) (*FunctionSnapshot, error) {
+	return app.loadSnapshot(ctx, location, name, version)
+}
 
 func (app *App) CallerIdentity() *CallerIdentity {
 	return app.callerIdentity
 }
 
+func (app *App) Masker() *masker {
+	return app.masker
+}
+
+func (app *App) CFnLookup() *CFnLookup {
+	return app.cfnLookup
+}
+
+func (m *masker) MaskAny(v any) any {
+	return m.maskAny(v)
+}
+
 func (app *App) LoadFunction(f string) (*Function, error) {
 	return app.loadFunction(f)
 }
+
+func (m *masker) Mask(s string) string {
+	return m.mask(s)
+}
+
+func (m *masker) MaskAnyPair(o, n any) (any, any) {
+	return m.maskAnyPair(o, n)
+}
+
+func (m *masker) Add(s string) {
+	m.add(s, "")
+}
+
+func (m *masker) SecretVariables(vars map[string]string) map[string]string {
+	return m.secretVariables(vars)
+}
+
+func (app *App) ListFunctionsOutput(ctx context.Context, opt *ListOption) (string, error) {
+	fns, err := app.listMatchedFunctions(ctx, opt)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	err = app.printListedFunctions(&b, fns, opt.Output)
+	return b.String(), err
+}
+
+func (app *App) ListFunctionNames(ctx context.Context, opt *ListOption) ([]string, error) {
+	fns, err := app.listMatchedFunctions(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fn := range fns {
+		names = append(names, *fn.Configuration.FunctionName)
+	}
+	return names, nil
+}
+
+func (app *App) InvokeStream(ctx context.Context, payload string, logTail bool, stdout, stderr io.Writer) error {
+	fn, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return err
+	}
+	return app.invokeStream(ctx, fn, []byte(payload), &InvokeOption{LogTail: logTail}, stdout, stderr)
+}
+
+func (app *App) NewCurlRequest(ctx context.Context, functionURL string, authType string, opt *CurlOption) (*http.Request, error) {
+	return app.newCurlRequest(ctx, functionURL, types.FunctionUrlAuthType(authType), opt)
+}
+
+var ParseSince = parseSince
+
+func (app *App) NewLogsTailer(logGroup, format, filterPattern string, w io.Writer) *logsTailer {
+	return app.newLogsTailer(logGroup, format, filterPattern, w)
+}
+
+func (t *logsTailer) SetFilter(f *logsFilter) {
+	t.filter = f
+}
+
+func (t *logsTailer) Tail(ctx context.Context, startTime int64) (int64, error) {
+	return t.tail(ctx, startTime)
+}
+
+var NewLogsFilter = newLogsFilter
+
+func (f *logsFilter) Apply(msg string) (string, bool, error) {
+	return f.apply(msg)
+}
+
+type LogsQueryResult = logsQueryResult
+
+func (app *App) RunLogsQuery(ctx context.Context, logGroup, query string, start, end time.Time, limit int32) (*logsQueryResult, error) {
+	return app.runLogsQuery(ctx, logGroup, query, start, end, limit)
+}
+
+var (
+	MetricsTable  = metricsTable
+	MetricsPeriod = metricsPeriod
+)
+
+func (app *App) GetFunctionMetrics(ctx context.Context, name string, qualifiers []string, start, end time.Time) ([]*FunctionMetrics, error) {
+	return app.getFunctionMetrics(ctx, name, qualifiers, start, end)
+}
+
+func (app *App) OverlayExcludes() []string {
+	return app.overlayExcludes()
+}
@@ -95,7 +95,7 @@ func TestLoadFunction(t *testing.T) {
 		},
 	}
 
-	for _, f := range []string{"test/function.json", "test/function.jsonnet"} {
+	for _, f := range []string{"test/function.json", "test/function.jsonnet", "test/function.yaml"} {
 		fn, err := app.LoadFunction(f)
 		if err != nil {
 			t.Error(err)
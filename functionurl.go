@@ -193,6 +193,26 @@ func (ps *PolicyStatement) PrincipalOrgID() *string {
 	return nil
 }
 
+// ConditionValue returns the string value of the key (case-insensitive) for the condition operator
+func (ps *PolicyStatement) ConditionValue(operator, key string) *string {
+	m, ok := ps.Condition.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	mm, ok := m[operator].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for k, v := range mm {
+		if strings.EqualFold(k, key) {
+			if s, ok := v.(string); ok {
+				return aws.String(s)
+			}
+		}
+	}
+	return nil
+}
+
 func (ps *PolicyStatement) SourceArn() *string {
 	if ps.Condition == nil {
 		return nil
@@ -225,7 +245,7 @@ func (ps *PolicyStatement) SourceArn() *string {
 }
 
 func (app *App) loadFunctionUrl(path string, functionName string) (*FunctionURL, error) {
-	f, err := loadDefinitionFile[FunctionURL](app, path, DefaultFunctionURLFilenames)
+	f, err := loadDefinitionFile[FunctionURL](app, path, DefaultFunctionURLFilenames, false)
 	if err != nil {
 		return nil, err
 	}
@@ -371,6 +391,33 @@ func (app *App) calcFunctionURLPermissionsDiff(ctx context.Context, fc *Function
 }
 
 func (app *App) getFunctionURLPermissions(ctx context.Context, functionName string, qualifier *string) (FunctionURLPermissions, error) {
+	statements, err := app.getPolicyStatements(ctx, functionName, qualifier)
+	if err != nil {
+		return nil, err
+	}
+	ps := make(FunctionURLPermissions, 0)
+	for _, s := range statements {
+		if s.Action != "lambda:InvokeFunctionUrl" || s.Effect != "Allow" {
+			// not a lambda function url policy
+			continue
+		}
+		st, _ := json.Marshal(s)
+		log.Println("[debug] exists sid", s.Sid, string(st))
+		ps = append(ps, &FunctionURLPermission{
+			sid: s.Sid,
+			AddPermissionInput: lambda.AddPermissionInput{
+				StatementId:    aws.String(s.Sid),
+				Principal:      s.PrincipalString(),
+				PrincipalOrgID: s.PrincipalOrgID(),
+				SourceArn:      s.SourceArn(),
+			},
+		})
+	}
+	return ps, nil
+}
+
+// getPolicyStatements returns the statements of the resource-based policy of the function (or the qualifier)
+func (app *App) getPolicyStatements(ctx context.Context, functionName string, qualifier *string) ([]PolicyStatement, error) {
 	fqFunctionName := fullQualifiedFunctionName(functionName, qualifier)
 	res, err := app.lambda.GetPolicy(ctx, &lambda.GetPolicyInput{
 		FunctionName: &functionName,
@@ -379,37 +426,16 @@ func (app *App) getFunctionURLPermissions(ctx context.Context, functionName stri
 	if err != nil {
 		var nfe *types.ResourceNotFoundException
 		if errors.As(err, &nfe) {
-			// do nothing
-		} else {
-			return nil, fmt.Errorf("failed to get policy: %w", err)
+			return nil, nil
 		}
+		return nil, fmt.Errorf("failed to get policy: %w", err)
 	}
-	ps := make(FunctionURLPermissions, 0)
-	if res != nil {
-		log.Printf("[debug] policy for %s: %s", fqFunctionName, *res.Policy)
-		var policy PolicyOutput
-		if err := json.Unmarshal([]byte(*res.Policy), &policy); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal policy: %w", err)
-		}
-		for _, s := range policy.Statement {
-			if s.Action != "lambda:InvokeFunctionUrl" || s.Effect != "Allow" {
-				// not a lambda function url policy
-				continue
-			}
-			st, _ := json.Marshal(s)
-			log.Println("[debug] exists sid", s.Sid, string(st))
-			ps = append(ps, &FunctionURLPermission{
-				sid: s.Sid,
-				AddPermissionInput: lambda.AddPermissionInput{
-					StatementId:    aws.String(s.Sid),
-					Principal:      s.PrincipalString(),
-					PrincipalOrgID: s.PrincipalOrgID(),
-					SourceArn:      s.SourceArn(),
-				},
-			})
-		}
+	log.Printf("[debug] policy for %s: %s", fqFunctionName, *res.Policy)
+	var policy PolicyOutput
+	if err := json.Unmarshal([]byte(*res.Policy), &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy: %w", err)
 	}
-	return ps, nil
+	return policy.Statement, nil
 }
 
 func (app *App) initFunctionURL(ctx context.Context, fn *Function, exists bool, opt *InitOption) error {
@@ -450,19 +476,12 @@ func (app *App) initFunctionURL(ctx context.Context, fn *Function, exists bool,
 	}
 	fu.Permissions = ps
 
-	var name string
-	if opt.Jsonnet {
-		name = DefaultFunctionURLFilenames[1]
-	} else {
-		name = DefaultFunctionURLFilenames[0]
-	}
+	name := definitionFilename(DefaultFunctionURLFilenames, opt.format())
 	log.Printf("[info] creating %s", name)
 	b, _ := marshalJSON(fu)
-	if opt.Jsonnet {
-		b, err = jsonToJsonnet(b, name)
-		if err != nil {
-			return err
-		}
+	b, err = convertDefinition(b, opt.format(), name)
+	if err != nil {
+		return err
 	}
 	if err := app.saveFile(name, b, os.FileMode(0644), opt.ForceOverwrite); err != nil {
 		return err
@@ -0,0 +1,574 @@
+package lambroll
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ImportOption represents options for Import()
+type ImportOption struct {
+	Template       string            `help:"path to SAM/CloudFormation template file" required:"true"`
+	Resource       string            `help:"logical ID of the function resource in the template" required:"true"`
+	Parameters     map[string]string `name:"parameter" help:"template parameter values (KEY=VALUE)"`
+	StackName      string            `help:"stack name for AWS::StackName pseudo parameter" default:""`
+	Jsonnet        bool              `help:"render function.json as jsonnet" default:"false"`
+	Format         string            `help:"format of definition files (json,jsonnet,yaml)" default:"json" enum:"json,jsonnet,yaml"`
+	ForceOverwrite bool              `help:"Overwrite existing files without prompting" default:"false"`
+}
+
+func (opt *ImportOption) format() string {
+	if opt.Jsonnet {
+		return "jsonnet"
+	}
+	return opt.Format
+}
+
+const (
+	cfnTypeServerlessFunction = "AWS::Serverless::Function"
+	cfnTypeLambdaFunction     = "AWS::Lambda::Function"
+	cfnTypeLambdaURL          = "AWS::Lambda::Url"
+	cfnTypeLambdaPermission   = "AWS::Lambda::Permission"
+)
+
+// Import creates function.json from a function resource in SAM/CloudFormation template
+func (app *App) Import(ctx context.Context, opt *ImportOption) error {
+	tmpl, err := readCFnTemplate(opt.Template)
+	if err != nil {
+		return err
+	}
+	r := newCFnResolver(tmpl, opt.Parameters)
+	r.pseudo = map[string]func() string{
+		"AWS::Region":    func() string { return app.awsConfig.Region },
+		"AWS::AccountId": func() string { return app.AWSAccountID(ctx) },
+	}
+	if opt.StackName != "" {
+		r.pseudo["AWS::StackName"] = func() string { return opt.StackName }
+	}
+	fn, fu, codeDir, err := importFunction(tmpl, opt.Resource, r, filepath.Dir(opt.Template))
+	if err != nil {
+		return err
+	}
+	if codeDir != "" {
+		log.Printf("[info] CodeUri is a local directory. deploy with `lambroll deploy --src %s`", codeDir)
+	}
+
+	log.Printf("[info] creating %s", IgnoreFilename)
+	err = app.saveFile(
+		IgnoreFilename,
+		[]byte(strings.Join(DefaultExcludes, "\n")+"\n"),
+		os.FileMode(0644),
+		opt.ForceOverwrite,
+	)
+	if err != nil {
+		return err
+	}
+
+	name := definitionFilename(DefaultFunctionFilenames, opt.format())
+	log.Printf("[info] creating %s", name)
+	b, _ := marshalJSON(fn)
+	if b, err = convertDefinition(b, opt.format(), name); err != nil {
+		return err
+	}
+	if err := app.saveFile(name, b, os.FileMode(0644), opt.ForceOverwrite); err != nil {
+		return err
+	}
+
+	if fu == nil {
+		return nil
+	}
+	name = definitionFilename(DefaultFunctionURLFilenames, opt.format())
+	log.Printf("[info] creating %s", name)
+	b, _ = marshalJSON(fu)
+	if b, err = convertDefinition(b, opt.format(), name); err != nil {
+		return err
+	}
+	return app.saveFile(name, b, os.FileMode(0644), opt.ForceOverwrite)
+}
+
+// readCFnTemplate reads a template in YAML or JSON.
+// The short form of intrinsic functions (e.g. !Ref) are converted to the full form.
+func readCFnTemplate(path string) (map[string]any, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	v, err := cfnNodeValue(&node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	tmpl, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("template %s is not an object", path)
+	}
+	return tmpl, nil
+}
+
+func cfnNodeValue(node *yaml.Node) (any, error) {
+	var v any
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return cfnNodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return cfnNodeValue(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			val, err := cfnNodeValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[node.Content[i].Value] = val
+		}
+		v = m
+	case yaml.SequenceNode:
+		l := make([]any, 0, len(node.Content))
+		for _, c := range node.Content {
+			val, err := cfnNodeValue(c)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, val)
+		}
+		v = l
+	case yaml.ScalarNode:
+		if strings.HasPrefix(node.Tag, "!!") || node.Tag == "" {
+			if err := node.Decode(&v); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+		v = node.Value
+	}
+	if tag := node.Tag; strings.HasPrefix(tag, "!") && !strings.HasPrefix(tag, "!!") {
+		name := strings.TrimPrefix(tag, "!")
+		switch name {
+		case "Ref", "Condition":
+		case "GetAtt":
+			if s, ok := v.(string); ok {
+				// !GetAtt Resource.Attribute
+				id, attr, _ := strings.Cut(s, ".")
+				v = []any{id, attr}
+			}
+			name = "Fn::" + name
+		default:
+			name = "Fn::" + name
+		}
+		return map[string]any{name: v}, nil
+	}
+	return v, nil
+}
+
+// cfnResolver resolves simple intrinsic functions (Ref, Fn::Sub, Fn::Join) in templates.
+type cfnResolver struct {
+	params map[string]any
+	pseudo map[string]func() string
+}
+
+func newCFnResolver(tmpl map[string]any, values map[string]string) *cfnResolver {
+	r := &cfnResolver{
+		params: make(map[string]any),
+		pseudo: make(map[string]func() string),
+	}
+	params, _ := tmpl["Parameters"].(map[string]any)
+	for name, p := range params {
+		pm, _ := p.(map[string]any)
+		var v any
+		if d, ok := pm["Default"]; ok {
+			v = d
+		}
+		if s, ok := values[name]; ok {
+			v = s
+		}
+		if v == nil {
+			continue
+		}
+		if typ, _ := pm["Type"].(string); strings.HasPrefix(typ, "List<") || typ == "CommaDelimitedList" {
+			if s, ok := v.(string); ok {
+				var l []any
+				for _, e := range strings.Split(s, ",") {
+					l = append(l, strings.TrimSpace(e))
+				}
+				v = l
+			}
+		}
+		r.params[name] = v
+	}
+	for name, v := range values {
+		if _, ok := r.params[name]; !ok {
+			r.params[name] = v
+		}
+	}
+	return r
+}
+
+func (r *cfnResolver) unresolved(fn string, arg any) string {
+	b, _ := json.Marshal(arg)
+	s := fmt.Sprintf("UNRESOLVED(%s %s)", fn, string(b))
+	log.Printf("[warn] unable to resolve %s %s. fix it in the definition manually", fn, string(b))
+	return s
+}
+
+func (r *cfnResolver) ref(name string) (any, bool) {
+	if v, ok := r.params[name]; ok {
+		return v, true
+	}
+	if f, ok := r.pseudo[name]; ok {
+		return f(), true
+	}
+	switch name {
+	case "AWS::Partition":
+		return "aws", true
+	case "AWS::URLSuffix":
+		return "amazonaws.com", true
+	case "AWS::NoValue":
+		return nil, true
+	}
+	return nil, false
+}
+
+var cfnSubPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+func (r *cfnResolver) sub(arg any) any {
+	var (
+		src  string
+		vars map[string]any
+	)
+	switch a := arg.(type) {
+	case string:
+		src = a
+	case []any:
+		if len(a) != 2 {
+			return r.unresolved("Fn::Sub", arg)
+		}
+		src, _ = a[0].(string)
+		vars, _ = r.resolve(a[1]).(map[string]any)
+	default:
+		return r.unresolved("Fn::Sub", arg)
+	}
+	failed := false
+	s := cfnSubPattern.ReplaceAllStringFunc(src, func(m string) string {
+		name := m[2 : len(m)-1]
+		if strings.HasPrefix(name, "!") {
+			return "${" + name[1:] + "}"
+		}
+		if v, ok := vars[name]; ok {
+			return fmt.Sprint(v)
+		}
+		if v, ok := r.ref(name); ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		failed = true
+		return m
+	})
+	if failed {
+		return r.unresolved("Fn::Sub", arg)
+	}
+	return s
+}
+
+func (r *cfnResolver) resolve(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		if len(vv) == 1 {
+			for k, arg := range vv {
+				switch {
+				case k == "Ref":
+					name, _ := arg.(string)
+					if v, ok := r.ref(name); ok {
+						return v
+					}
+					return r.unresolved(k, arg)
+				case k == "Fn::Sub":
+					return r.sub(arg)
+				case k == "Fn::Join":
+					a, ok := arg.([]any)
+					if !ok || len(a) != 2 {
+						return r.unresolved(k, arg)
+					}
+					sep, _ := a[0].(string)
+					items, ok := r.resolve(a[1]).([]any)
+					if !ok {
+						return r.unresolved(k, arg)
+					}
+					ss := make([]string, 0, len(items))
+					for _, item := range items {
+						ss = append(ss, fmt.Sprint(item))
+					}
+					return strings.Join(ss, sep)
+				case strings.HasPrefix(k, "Fn::"):
+					return r.unresolved(k, arg)
+				}
+			}
+		}
+		m := make(map[string]any, len(vv))
+		for k, val := range vv {
+			if rv := r.resolve(val); rv != nil {
+				m[k] = rv
+			}
+		}
+		return m
+	case []any:
+		l := make([]any, 0, len(vv))
+		for _, val := range vv {
+			if rv := r.resolve(val); rv != nil {
+				l = append(l, rv)
+			}
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// functionProperties are the properties of the function resource imported as-is.
+var functionProperties = []string{
+	"Architectures", "CodeSigningConfigArn", "DeadLetterConfig", "Description",
+	"Environment", "EphemeralStorage", "FileSystemConfigs", "FunctionName",
+	"Handler", "ImageConfig", "Layers", "LoggingConfig", "MemorySize",
+	"PackageType", "Role", "Runtime", "SnapStart", "Timeout", "TracingConfig",
+	"VpcConfig",
+}
+
+// importFunction converts a function resource in the template into Function and FunctionURL.
+// When CodeUri is a local directory, codeDir is returned.
+func importFunction(tmpl map[string]any, logicalID string, r *cfnResolver, baseDir string) (fn *Function, fu *FunctionURL, codeDir string, err error) {
+	resources, _ := tmpl["Resources"].(map[string]any)
+	res, _ := resources[logicalID].(map[string]any)
+	if res == nil {
+		return nil, nil, "", fmt.Errorf("resource %s is not found in the template", logicalID)
+	}
+	typ, _ := res["Type"].(string)
+	props, _ := res["Properties"].(map[string]any)
+	if props == nil {
+		props = map[string]any{}
+	}
+	switch typ {
+	case cfnTypeServerlessFunction:
+		if globals, ok := tmpl["Globals"].(map[string]any); ok {
+			if gf, ok := globals["Function"].(map[string]any); ok {
+				props = mergeValues(gf, props, true).(map[string]any)
+			}
+		}
+	case cfnTypeLambdaFunction:
+	default:
+		return nil, nil, "", fmt.Errorf("resource %s is not a function (%s)", logicalID, typ)
+	}
+	props = r.resolve(props).(map[string]any)
+
+	def := make(map[string]any)
+	for _, key := range functionProperties {
+		if v, ok := props[key]; ok {
+			def[key] = v
+			delete(props, key)
+		}
+	}
+	if v, ok := props["KmsKeyArn"]; ok {
+		def["KMSKeyArn"] = v
+		delete(props, "KmsKeyArn")
+	}
+	if v, ok := props["Tracing"].(string); ok {
+		def["TracingConfig"] = map[string]any{"Mode": v}
+		delete(props, "Tracing")
+	}
+	if v, ok := props["DeadLetterQueue"].(map[string]any); ok {
+		def["DeadLetterConfig"] = map[string]any{"TargetArn": v["TargetArn"]}
+		delete(props, "DeadLetterQueue")
+	}
+	switch tags := props["Tags"].(type) {
+	case map[string]any:
+		def["Tags"] = tags
+	case []any:
+		m := make(map[string]any, len(tags))
+		for _, t := range tags {
+			if tm, ok := t.(map[string]any); ok {
+				m[fmt.Sprint(tm["Key"])] = tm["Value"]
+			}
+		}
+		def["Tags"] = m
+	}
+	delete(props, "Tags")
+
+	code := make(map[string]any)
+	if c, ok := props["Code"].(map[string]any); ok {
+		for _, key := range []string{"S3Bucket", "S3Key", "S3ObjectVersion", "ImageUri"} {
+			if v, ok := c[key]; ok {
+				code[key] = v
+			}
+		}
+		if _, ok := c["ZipFile"]; ok {
+			log.Printf("[warn] inline code (ZipFile) is not supported. put the code into a file")
+		}
+		delete(props, "Code")
+	}
+	switch uri := props["CodeUri"].(type) {
+	case string:
+		if strings.HasPrefix(uri, "s3://") {
+			bucket, key, _ := strings.Cut(strings.TrimPrefix(uri, "s3://"), "/")
+			code["S3Bucket"] = bucket
+			code["S3Key"] = key
+		} else if filepath.IsAbs(uri) {
+			codeDir = uri
+		} else {
+			codeDir = filepath.Join(baseDir, uri)
+		}
+	case map[string]any:
+		code["S3Bucket"] = uri["Bucket"]
+		code["S3Key"] = uri["Key"]
+		if v, ok := uri["Version"]; ok {
+			code["S3ObjectVersion"] = v
+		}
+	}
+	delete(props, "CodeUri")
+	if v, ok := props["ImageUri"]; ok {
+		code["ImageUri"] = v
+		def["PackageType"] = string(types.PackageTypeImage)
+		delete(props, "ImageUri")
+	}
+	if _, ok := props["InlineCode"]; ok {
+		log.Printf("[warn] InlineCode is not supported. put the code into a file")
+		delete(props, "InlineCode")
+	}
+	if len(code) > 0 {
+		def["Code"] = code
+	}
+
+	urlConfig, _ := props["FunctionUrlConfig"].(map[string]any)
+	delete(props, "FunctionUrlConfig")
+
+	ignored := make([]string, 0, len(props))
+	for key := range props {
+		ignored = append(ignored, key)
+	}
+	sort.Strings(ignored)
+	for _, key := range ignored {
+		log.Printf("[warn] property %s of %s is not supported by lambroll. ignored", key, logicalID)
+	}
+
+	if _, ok := def["Role"]; !ok {
+		log.Printf("[warn] Role is not defined in %s. set the role ARN in the definition", logicalID)
+		def["Role"] = "arn:aws:iam::{{ caller_identity.Account }}:role/YOUR_LAMBDA_ROLE_NAME"
+	}
+	if _, ok := def["FunctionName"]; !ok {
+		def["FunctionName"] = logicalID
+	}
+
+	b, err := json.Marshal(def)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	fn = &Function{}
+	if err := unmarshalJSON(b, fn, logicalID); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to convert %s to function: %w", logicalID, err)
+	}
+
+	// function URL
+	var qualifier any
+	for id, v := range resources {
+		res, _ := v.(map[string]any)
+		if t, _ := res["Type"].(string); t != cfnTypeLambdaURL {
+			continue
+		}
+		p, _ := res["Properties"].(map[string]any)
+		if !cfnRefersTo(p["TargetFunctionArn"], logicalID) {
+			continue
+		}
+		log.Printf("[info] function url %s found", id)
+		urlConfig, _ = r.resolve(p).(map[string]any)
+		qualifier = urlConfig["Qualifier"]
+	}
+	if urlConfig != nil {
+		config := map[string]any{}
+		for _, key := range []string{"AuthType", "Cors", "InvokeMode"} {
+			if v, ok := urlConfig[key]; ok {
+				config[key] = v
+			}
+		}
+		if qualifier != nil {
+			config["Qualifier"] = qualifier
+		}
+		fu = &FunctionURL{}
+		b, _ := json.Marshal(map[string]any{"Config": config})
+		if err := unmarshalJSON(b, fu, logicalID); err != nil {
+			return nil, nil, "", fmt.Errorf("failed to convert function url config of %s: %w", logicalID, err)
+		}
+		for id, v := range resources {
+			res, _ := v.(map[string]any)
+			if t, _ := res["Type"].(string); t != cfnTypeLambdaPermission {
+				continue
+			}
+			p, _ := res["Properties"].(map[string]any)
+			if action, _ := p["Action"].(string); action != "lambda:InvokeFunctionUrl" || !cfnRefersTo(p["FunctionName"], logicalID) {
+				continue
+			}
+			log.Printf("[info] function url permission %s found", id)
+			p, _ = r.resolve(p).(map[string]any)
+			perm := &FunctionURLPermission{}
+			for key, dst := range map[string]**string{
+				"Principal":      &perm.Principal,
+				"PrincipalOrgID": &perm.PrincipalOrgID,
+				"SourceArn":      &perm.SourceArn,
+				"SourceAccount":  &perm.SourceAccount,
+			} {
+				if v, ok := p[key].(string); ok {
+					*dst = aws.String(v)
+				}
+			}
+			fu.Permissions = append(fu.Permissions, perm)
+		}
+		if len(fu.Permissions) == 0 && fu.Config.AuthType == types.FunctionUrlAuthTypeNone {
+			fu.Permissions = append(fu.Permissions, &FunctionURLPermission{
+				AddPermissionInput: lambda.AddPermissionInput{
+					Principal: aws.String("*"),
+				},
+			})
+		}
+	}
+	return fn, fu, codeDir, nil
+}
+
+// cfnRefersTo reports whether v refers to the resource (Ref, Fn::GetAtt or Fn::Join/Fn::Sub including them).
+func cfnRefersTo(v any, logicalID string) bool {
+	switch vv := v.(type) {
+	case map[string]any:
+		if ref, ok := vv["Ref"].(string); ok {
+			return ref == logicalID
+		}
+		if ga, ok := vv["Fn::GetAtt"].([]any); ok && len(ga) > 0 {
+			return ga[0] == logicalID
+		}
+		if s, ok := vv["Fn::Sub"].(string); ok {
+			return strings.Contains(s, "${"+logicalID+"}") || strings.Contains(s, "${"+logicalID+".")
+		}
+		for _, val := range vv {
+			if cfnRefersTo(val, logicalID) {
+				return true
+			}
+		}
+	case []any:
+		for _, val := range vv {
+			if cfnRefersTo(val, logicalID) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package lambroll_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImportFunction(t *testing.T) {
+	tmpl, err := lambroll.ReadCFnTemplate("test/template.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := lambroll.NewCFnResolver(tmpl, map[string]string{"Env": "prod"})
+	fn, fu, codeDir, err := lambroll.ImportFunction(tmpl, "HelloFunction", r, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codeDir != "test/src" {
+		t.Errorf("unexpected code dir: %s", codeDir)
+	}
+	expected := &lambroll.Function{
+		FunctionName: aws.String("hello-prod"),
+		Handler:      aws.String("index.handler"),
+		Role:         aws.String("arn:aws:iam::123456789012:role/hello-prod"),
+		Runtime:      types.RuntimeNodejs20x,
+		MemorySize:   aws.Int32(256),
+		Timeout:      aws.Int32(10),
+		Environment: &types.Environment{
+			Variables: map[string]string{
+				"ENV":   "prod",
+				"FOO":   "foo",
+				"TABLE": `UNRESOLVED(Ref "Table")`,
+			},
+		},
+		Layers: []string{
+			"UNRESOLVED(Fn::Sub \"arn:aws:lambda:${AWS::Region}:123456789012:layer:common:1\")",
+			"arn:aws:lambda:ap-northeast-1:123456789012:layer:hello:2",
+		},
+		TracingConfig: &types.TracingConfig{Mode: types.TracingModeActive},
+		VpcConfig: &types.VpcConfig{
+			SubnetIds:        []string{"subnet-a", "subnet-b"},
+			SecurityGroupIds: []string{"sg-01a9b01eab0a3c154"},
+		},
+		Tags: map[string]string{"Env": "prod"},
+	}
+	expectedJSON, _ := lambroll.MarshalJSON(expected)
+	fnJSON, _ := lambroll.MarshalJSON(fn)
+	if diff := cmp.Diff(string(expectedJSON), string(fnJSON)); diff != "" {
+		t.Errorf("unexpected function (-expected +got)\n%s", diff)
+	}
+	if fu == nil {
+		t.Fatal("function url is not imported")
+	}
+	if fu.Config.AuthType != types.FunctionUrlAuthTypeNone {
+		t.Errorf("unexpected auth type: %s", fu.Config.AuthType)
+	}
+	if len(fu.Permissions) != 1 || aws.ToString(fu.Permissions[0].Principal) != "*" {
+		t.Errorf("unexpected permissions: %v", fu.Permissions)
+	}
+}
@@ -20,11 +20,31 @@ type InitOption struct {
 	FunctionName   *string `help:"Function name for init" required:"true" default:""`
 	DownloadZip    bool    `name:"download" help:"Download function.zip" default:"false"`
 	Jsonnet        bool    `help:"render function.json as jsonnet" default:"false"`
+	Format         string  `help:"format of definition files (json,jsonnet,yaml)" default:"json" enum:"json,jsonnet,yaml"`
 	Qualifier      *string `help:"function version or alias"`
 	FunctionURL    bool    `help:"create function url definition file" default:"false"`
 	ForceOverwrite bool    `help:"Overwrite existing files without prompting" default:"false"`
 }
 
+func (opt *InitOption) format() string {
+	if opt.Jsonnet {
+		return "jsonnet"
+	}
+	return opt.Format
+}
+
+// definitionFilename returns the file name for the format from defaults (json, jsonnet, yaml)
+func definitionFilename(defaults []string, format string) string {
+	switch format {
+	case "jsonnet":
+		return defaults[1]
+	case "yaml":
+		return defaults[2]
+	default:
+		return defaults[0]
+	}
+}
+
 // Init initializes function.json
 func (app *App) Init(ctx context.Context, opt *InitOption) error {
 	res, err := app.lambda.GetFunction(ctx, &lambda.GetFunctionInput{
@@ -97,19 +117,12 @@ func (app *App) Init(ctx context.Context, opt *InitOption) error {
 		return err
 	}
 
-	var name string
-	if opt.Jsonnet {
-		name = DefaultFunctionFilenames[1]
-	} else {
-		name = DefaultFunctionFilenames[0]
-	}
+	name := definitionFilename(DefaultFunctionFilenames, opt.format())
 	log.Printf("[info] creating %s", name)
 	b, _ := marshalJSON(fn)
-	if opt.Jsonnet {
-		b, err = jsonToJsonnet(b, name)
-		if err != nil {
-			return err
-		}
+	b, err = convertDefinition(b, opt.format(), name)
+	if err != nil {
+		return err
 	}
 	if err := app.saveFile(name, b, os.FileMode(0644), opt.ForceOverwrite); err != nil {
 		return err
@@ -130,10 +143,14 @@ func download(url, path string) error {
 		return fmt.Errorf("failed to get %s: %w", url, err)
 	}
 	defer resp.Body.Close()
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, os.FileMode(0644))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: %s", url, resp.Status)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(0644))
 	if err != nil {
 		return fmt.Errorf("failed to open file %s: %w", path, err)
 	}
+	defer f.Close()
 	_, err = io.Copy(f, resp.Body)
 	return err
 }
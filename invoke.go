@@ -10,9 +10,11 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mattn/go-isatty"
 
+	"github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 )
@@ -23,6 +25,15 @@ type InvokeOption struct {
 	LogTail   bool    `default:"false" help:"output tail of log to STDERR"`
 	Qualifier *string `help:"version or alias to invoke"`
 	Payload   *string `help:"payload to invoke. if not specified, read from STDIN"`
+	Stream    bool    `default:"false" help:"invoke with response stream (InvokeWithResponseStream)"`
+	FailFast  bool    `default:"false" help:"stop invoking at the first failure"`
+
+	PayloadFile  string        `help:"file to read payloads. if not specified, read from STDIN" default:""`
+	Count        int           `help:"number of invocations for load testing" default:"0"`
+	Concurrency  int           `help:"number of concurrent invocations for load testing" default:"1"`
+	Rate         float64       `help:"invocations per second for load testing (requires --duration)" default:"0"`
+	Duration     time.Duration `help:"duration of load testing with --rate (e.g. 30s)" default:"0s"`
+	MaxErrorRate float64       `help:"maximum percentage of failed invocations to exit with status 0 in load testing" default:"0"`
 }
 
 // Invoke invokes function
@@ -42,18 +53,25 @@ func (app *App) Invoke(ctx context.Context, opt *InvokeOption) error {
 		logType = types.LogTypeTail
 	}
 
-	var payloadSrc io.Reader
-	if opt.Payload != nil {
-		payloadSrc = strings.NewReader(*opt.Payload)
-	} else {
-		if isatty.IsTerminal(os.Stdin.Fd()) {
-			fmt.Println("Enter JSON payloads for the invoking function into STDIN. (Type Ctrl-D to close.)")
-		}
-		payloadSrc = os.Stdin
+	payloadSrc, closer, err := openPayloadSource(opt.Payload, opt.PayloadFile)
+	if err != nil {
+		return err
 	}
+	defer closer()
 	dec := json.NewDecoder(payloadSrc)
+	if opt.Stream && (opt.Async || opt.loadTest()) {
+		return fmt.Errorf("--stream cannot be used with --async or the load test mode")
+	}
+	if opt.loadTest() {
+		payloads, err := readPayloads(dec)
+		if err != nil {
+			return err
+		}
+		return app.invokeLoadTest(ctx, fn, opt, payloads)
+	}
 	stdout := bufio.NewWriter(os.Stdout)
 	stderr := bufio.NewWriter(os.Stderr)
+	var results []*invokeResult
 PAYLOAD:
 	for {
 		var payload interface{}
@@ -65,33 +83,149 @@ PAYLOAD:
 			return fmt.Errorf("failed to decode payload as JSON: %w", err)
 		}
 		b, _ := json.Marshal(payload)
-		in := &lambda.InvokeInput{
-			FunctionName:   fn.FunctionName,
-			InvocationType: invocationType,
-			LogType:        logType,
-			Payload:        b,
+		result := &invokeResult{Index: len(results) + 1}
+		results = append(results, result)
+		if opt.Stream {
+			result.Err = app.invokeStream(ctx, fn, b, opt, stdout, stderr)
+		} else {
+			result.Err = app.invoke(ctx, fn, b, invocationType, logType, opt, result, stdout, stderr)
 		}
-		in.Qualifier = opt.Qualifier
-		log.Println("[debug] invoking function", in)
-		res, err := app.lambda.Invoke(ctx, in)
-		if err != nil {
-			log.Println("[error] failed to invoke function", err.Error())
-			continue PAYLOAD
+		if result.Err != nil {
+			log.Printf("[error] failed to invoke function: %s", result.Err)
+		} else if result.FunctionError != "" {
+			fmt.Fprintf(stderr, "FunctionError:%s %s\n", result.FunctionError, result.ErrorMessage)
+			stderr.Flush()
 		}
-		stdout.Write(res.Payload)
-		stdout.Write([]byte("\n"))
-		stdout.Flush()
-
-		log.Printf("[info] StatusCode:%d", res.StatusCode)
-		if res.ExecutedVersion != nil {
-			log.Printf("[info] ExecutionVersion:%s", *res.ExecutedVersion)
+		if opt.FailFast && result.failed() {
+			log.Println("[warn] stop invoking by --fail-fast")
+			break PAYLOAD
 		}
-		if res.LogResult != nil {
-			b, _ := base64.StdEncoding.DecodeString(*res.LogResult)
-			stderr.Write(b)
-			stderr.Flush()
+	}
+
+	return summarizeInvokeResults(results)
+}
+
+// invokeResult represents the result of an invocation for a payload
+type invokeResult struct {
+	Index         int
+	FunctionError string
+	ErrorMessage  string
+	Err           error
+}
+
+func (r *invokeResult) failed() bool {
+	return r.Err != nil || r.FunctionError != ""
+}
+
+func (r *invokeResult) setFunctionError(functionError string, payload []byte) {
+	r.FunctionError = functionError
+	var fe functionErrorPayload
+	if err := json.Unmarshal(payload, &fe); err == nil && fe.ErrorMessage != "" {
+		r.ErrorMessage = fe.ErrorType + ": " + fe.ErrorMessage
+	} else {
+		r.ErrorMessage = string(payload)
+	}
+}
+
+func (r *invokeResult) String() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("#%d error: %s", r.Index, r.Err)
+	case r.FunctionError != "":
+		return fmt.Sprintf("#%d FunctionError:%s %s", r.Index, r.FunctionError, r.ErrorMessage)
+	default:
+		return fmt.Sprintf("#%d ok", r.Index)
+	}
+}
+
+// functionErrorPayload represents the payload of the function error
+type functionErrorPayload struct {
+	ErrorMessage string `json:"errorMessage"`
+	ErrorType    string `json:"errorType"`
+}
+
+func (app *App) invoke(ctx context.Context, fn *Function, payload []byte, invocationType types.InvocationType, logType types.LogType, opt *InvokeOption, result *invokeResult, stdout, stderr *bufio.Writer) error {
+	in := &lambda.InvokeInput{
+		FunctionName:   fn.FunctionName,
+		InvocationType: invocationType,
+		LogType:        logType,
+		Payload:        payload,
+	}
+	in.Qualifier = opt.Qualifier
+	log.Println("[debug] invoking function", in)
+	res, err := app.lambda.Invoke(ctx, in)
+	if err != nil {
+		return err
+	}
+	stdout.Write(res.Payload)
+	stdout.Write([]byte("\n"))
+	stdout.Flush()
+
+	log.Printf("[info] StatusCode:%d", res.StatusCode)
+	if id, ok := middleware.GetRequestIDMetadata(res.ResultMetadata); ok {
+		log.Printf("[info] RequestId:%s", id)
+	}
+	if res.ExecutedVersion != nil {
+		log.Printf("[info] ExecutionVersion:%s", *res.ExecutedVersion)
+	}
+	if res.LogResult != nil {
+		b, _ := base64.StdEncoding.DecodeString(*res.LogResult)
+		stderr.Write(b)
+		stderr.Flush()
+	}
+	if res.FunctionError != nil {
+		result.setFunctionError(*res.FunctionError, res.Payload)
+	}
+	return nil
+}
+
+// openPayloadSource opens the source of payloads from the payload string, the file or STDIN
+func openPayloadSource(payload *string, payloadFile string) (io.Reader, func() error, error) {
+	if payload != nil {
+		return strings.NewReader(*payload), func() error { return nil }, nil
+	}
+	if payloadFile != "" {
+		f, err := os.Open(payloadFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open payload file: %w", err)
 		}
+		return f, f.Close, nil
 	}
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		fmt.Println("Enter JSON payloads for the invoking function into STDIN. (Type Ctrl-D to close.)")
+	}
+	return os.Stdin, func() error { return nil }, nil
+}
 
+// summarizeInvokeResults logs the results and returns an error if any invocation failed
+func summarizeInvokeResults(results []*invokeResult) error {
+	var failed int
+	for _, r := range results {
+		if r.failed() {
+			failed++
+			log.Printf("[error] %s", r)
+		} else {
+			log.Printf("[debug] %s", r)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d invocations failed", failed, len(results))
+	}
+	log.Printf("[info] %d invocations succeeded", len(results))
 	return nil
 }
+
+func readPayloads(dec *json.Decoder) ([][]byte, error) {
+	var payloads [][]byte
+	for {
+		var payload interface{}
+		if err := dec.Decode(&payload); err != nil {
+			if err == io.EOF {
+				return payloads, nil
+			}
+			return nil, fmt.Errorf("failed to decode payload as JSON: %w", err)
+		}
+		b, _ := json.Marshal(payload)
+		payloads = append(payloads, b)
+	}
+}
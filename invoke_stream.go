@@ -0,0 +1,84 @@
+package lambroll
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// invokeStream invokes the function with InvokeWithResponseStream and writes chunks to stdout as they arrive.
+func (app *App) invokeStream(ctx context.Context, fn *Function, payload []byte, opt *InvokeOption, stdout, stderr io.Writer) error {
+	in := &lambda.InvokeWithResponseStreamInput{
+		FunctionName:   fn.FunctionName,
+		InvocationType: types.ResponseStreamingInvocationTypeRequestResponse,
+		Payload:        payload,
+		Qualifier:      opt.Qualifier,
+	}
+	if opt.LogTail {
+		in.LogType = types.LogTypeTail
+	}
+	log.Println("[debug] invoking function with response stream", in)
+	res, err := app.lambda.InvokeWithResponseStream(ctx, in)
+	if err != nil {
+		return fmt.Errorf("InvokeWithResponseStream: %w", err)
+	}
+	log.Printf("[info] StatusCode:%d", res.StatusCode)
+	if id, ok := middleware.GetRequestIDMetadata(res.ResultMetadata); ok {
+		log.Printf("[info] RequestId:%s", id)
+	}
+	if res.ExecutedVersion != nil {
+		log.Printf("[info] ExecutionVersion:%s", *res.ExecutedVersion)
+	}
+	if res.ResponseStreamContentType != nil {
+		log.Printf("[debug] ResponseStreamContentType:%s", *res.ResponseStreamContentType)
+	}
+
+	stream := res.GetStream()
+	defer stream.Close()
+	var complete *types.InvokeWithResponseStreamCompleteEvent
+	for event := range stream.Events() {
+		switch v := event.(type) {
+		case *types.InvokeWithResponseStreamResponseEventMemberPayloadChunk:
+			if _, err := stdout.Write(v.Value.Payload); err != nil {
+				return fmt.Errorf("failed to write payload chunk: %w", err)
+			}
+			flush(stdout)
+		case *types.InvokeWithResponseStreamResponseEventMemberInvokeComplete:
+			complete = &v.Value
+		default:
+			log.Printf("[warn] unknown event type %T", v)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return fmt.Errorf("failed to read response stream: %w", err)
+	}
+	stdout.Write([]byte("\n"))
+	flush(stdout)
+
+	if complete == nil {
+		return fmt.Errorf("response stream was closed without InvokeComplete event")
+	}
+	if complete.LogResult != nil {
+		b, _ := base64.StdEncoding.DecodeString(*complete.LogResult)
+		stderr.Write(b)
+		flush(stderr)
+	}
+	if code := aws.ToString(complete.ErrorCode); code != "" {
+		return fmt.Errorf("InvokeComplete ErrorCode:%s ErrorDetails:%s", code, aws.ToString(complete.ErrorDetails))
+	}
+	log.Println("[info] InvokeComplete")
+	return nil
+}
+
+func flush(w io.Writer) {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,74 @@
+package lambroll_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/fujiwara/lambroll"
+)
+
+func writeTestEvent(t *testing.T, w http.ResponseWriter, eventType string, payload []byte) {
+	t.Helper()
+	msg := eventstream.Message{Payload: payload}
+	msg.Headers.Set(":message-type", eventstream.StringValue("event"))
+	msg.Headers.Set(":event-type", eventstream.StringValue(eventType))
+	var buf bytes.Buffer
+	if err := eventstream.NewEncoder().Encode(&buf, msg); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(buf.Bytes())
+	w.(http.Flusher).Flush()
+}
+
+func TestInvokeStream(t *testing.T) {
+	var completeBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/response-streaming-invocations") {
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Header().Set("X-Amz-Executed-Version", "$LATEST")
+		w.WriteHeader(http.StatusOK)
+		writeTestEvent(t, w, "PayloadChunk", []byte("hello "))
+		writeTestEvent(t, w, "PayloadChunk", []byte("world"))
+		writeTestEvent(t, w, "InvokeComplete", []byte(completeBody))
+	}))
+	defer ts.Close()
+
+	fnPath := filepath.Join(t.TempDir(), "function.json")
+	if err := os.WriteFile(fnPath, []byte(`{"FunctionName":"hello"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	app := newTestAppWithOption(t, ts, &lambroll.Option{Function: fnPath})
+
+	logResult := base64.StdEncoding.EncodeToString([]byte("REPORT RequestId: 1\tDuration: 1.00 ms\n"))
+	completeBody = fmt.Sprintf(`{"LogResult":%q}`, logResult)
+	var stdout, stderr bytes.Buffer
+	if err := app.InvokeStream(ctx, `{"foo":1}`, true, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if s := stdout.String(); s != "hello world\n" {
+		t.Errorf("unexpected stdout: %q", s)
+	}
+	if s := stderr.String(); !strings.HasPrefix(s, "REPORT RequestId: 1") {
+		t.Errorf("unexpected stderr: %q", s)
+	}
+
+	completeBody = `{"ErrorCode":"Runtime.ExitError","ErrorDetails":"exit status 1"}`
+	err := app.InvokeStream(ctx, `{"foo":1}`, false, &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "Runtime.ExitError") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package lambroll_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/fujiwara/lambroll"
+)
+
+func TestInvokeFunctionError(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(b), "fail") {
+			w.Header().Set("X-Amz-Function-Error", "Unhandled")
+			fmt.Fprint(w, `{"errorMessage":"something wrong","errorType":"Error"}`)
+			return
+		}
+		fmt.Fprint(w, `"ok"`)
+	}))
+	defer ts.Close()
+
+	fnPath := filepath.Join(t.TempDir(), "function.json")
+	if err := os.WriteFile(fnPath, []byte(`{"FunctionName":"hello"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	app := newTestAppWithOption(t, ts, &lambroll.Option{Function: fnPath})
+
+	tests := []struct {
+		name     string
+		payload  string
+		failFast bool
+		calls    int32
+		errMsg   string
+	}{
+		{name: "ok", payload: `{"a":1} {"a":2}`, calls: 2},
+		{name: "function error", payload: `{"a":"fail"} {"a":2}`, calls: 2, errMsg: "1 of 2 invocations failed"},
+		{name: "fail fast", payload: `{"a":"fail"} {"a":2}`, failFast: true, calls: 1, errMsg: "1 of 1 invocations failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			err := app.Invoke(ctx, &lambroll.InvokeOption{
+				Payload:  aws.String(tt.payload),
+				FailFast: tt.failFast,
+			})
+			if tt.errMsg == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if tt.errMsg != "" && (err == nil || err.Error() != tt.errMsg) {
+				t.Errorf("unexpected error: %v, expected %s", err, tt.errMsg)
+			}
+			if n := atomic.LoadInt32(&calls); n != tt.calls {
+				t.Errorf("unexpected invocations: %d, expected %d", n, tt.calls)
+			}
+		})
+	}
+}
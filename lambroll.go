@@ -11,11 +11,13 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
-	"github.com/fujiwara/ssm-lookup/ssm"
 	"github.com/fujiwara/tfstate-lookup/tfstate"
 	"github.com/google/go-jsonnet"
 	"github.com/hashicorp/go-envparse"
@@ -60,11 +62,13 @@ var (
 	DefaultFunctionFilenames = []string{
 		"function.json",
 		"function.jsonnet",
+		"function.yaml",
 	}
 
 	DefaultFunctionURLFilenames = []string{
 		"function_url.json",
 		"function_url.jsonnet",
+		"function_url.yaml",
 	}
 
 	// FunctionZipFilename defines file name for zip archive downloaded at init.
@@ -75,8 +79,10 @@ var (
 		IgnoreFilename,
 		DefaultFunctionFilenames[0],
 		DefaultFunctionFilenames[1],
+		DefaultFunctionFilenames[2],
 		DefaultFunctionURLFilenames[0],
 		DefaultFunctionURLFilenames[1],
+		DefaultFunctionURLFilenames[2],
 		FunctionZipFilename,
 		".git/*",
 		".terraform/*",
@@ -90,7 +96,9 @@ var (
 // App represents lambroll application
 type App struct {
 	callerIdentity *CallerIdentity
-	profile        string
+	secretsManager *SecretsManager
+	cfnLookup      *CFnLookup
+	masker         *masker
 	loader         *config.Loader
 
 	awsConfig aws.Config
@@ -100,7 +108,9 @@ type App struct {
 	extCode     map[string]string
 	nativeFuncs []*jsonnet.NativeFunction
 
-	functionFilePath string
+	functionFilePath  string
+	env               string
+	overlayArrayMerge string
 }
 
 func newAwsConfig(ctx context.Context, opt *Option) (aws.Config, error) {
@@ -113,7 +123,8 @@ func newAwsConfig(ctx context.Context, opt *Option) (aws.Config, error) {
 	}
 	if opt.Endpoint != nil && *opt.Endpoint != "" {
 		customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-			if service == lambda.ServiceID || service == sts.ServiceID || service == s3.ServiceID {
+			switch service {
+			case lambda.ServiceID, sts.ServiceID, s3.ServiceID, cloudformation.ServiceID, cloudwatch.ServiceID, cloudwatchlogs.ServiceID:
 				return aws.Endpoint{
 					PartitionID:   "aws",
 					URL:           *opt.Endpoint,
@@ -144,25 +155,15 @@ func New(ctx context.Context, opt *Option) (*App, error) {
 		return nil, err
 	}
 
-	var profile string
-	if opt.Profile != nil && *opt.Profile != "" {
-		profile = *opt.Profile
-	}
-
 	loader := config.New()
 	nativeFuncs := DefaultJsonnetNativeFuncs()
 
+	masker := newMasker(opt.MaskKeys)
+
 	// load ssm functions
-	if ssmFuncs, err := ssm.FuncMap(ctx, v2cfg); err != nil {
-		return nil, err
-	} else {
-		loader.Funcs(ssmFuncs)
-	}
-	if ssmNativeFuncs, err := ssm.JsonnetNativeFuncs(ctx, v2cfg); err != nil {
-		return nil, err
-	} else {
-		nativeFuncs = append(nativeFuncs, ssmNativeFuncs...)
-	}
+	ssmLookup := newSSMLookup(v2cfg, masker)
+	loader.Funcs(ssmLookup.FuncMap(ctx))
+	nativeFuncs = append(nativeFuncs, ssmLookup.JsonnetNativeFuncs(ctx)...)
 
 	// load tfstate functions
 	if opt.TFState != nil && *opt.TFState != "" {
@@ -191,20 +192,33 @@ func New(ctx context.Context, opt *Option) (*App, error) {
 		loader.Funcs(prefixedFuncs)
 	}
 
+	// load cloudformation functions
+	cfnLookup := newCFnLookup(v2cfg)
+	loader.Funcs(cfnLookup.FuncMap(ctx))
+	nativeFuncs = append(nativeFuncs, cfnLookup.JsonnetNativeFuncs(ctx)...)
+
 	callerIdentity := newCallerIdentity(v2cfg)
 	nativeFuncs = append(nativeFuncs, callerIdentity.JsonnetNativeFuncs(ctx)...)
 	loader.Funcs(callerIdentity.FuncMap(ctx))
 
+	secretsManager := newSecretsManager(v2cfg, masker)
+	nativeFuncs = append(nativeFuncs, secretsManager.JsonnetNativeFuncs(ctx)...)
+	loader.Funcs(secretsManager.FuncMap(ctx))
+
 	app := &App{
-		callerIdentity:   callerIdentity,
-		profile:          profile,
-		loader:           loader,
-		awsConfig:        v2cfg,
-		lambda:           lambda.NewFromConfig(v2cfg),
-		functionFilePath: opt.Function,
-		nativeFuncs:      nativeFuncs,
-		extStr:           opt.ExtStr,
-		extCode:          opt.ExtCode,
+		callerIdentity:    callerIdentity,
+		secretsManager:    secretsManager,
+		cfnLookup:         cfnLookup,
+		masker:            masker,
+		loader:            loader,
+		awsConfig:         v2cfg,
+		lambda:            lambda.NewFromConfig(v2cfg),
+		functionFilePath:  opt.Function,
+		nativeFuncs:       nativeFuncs,
+		extStr:            opt.ExtStr,
+		extCode:           opt.ExtCode,
+		env:               opt.Env,
+		overlayArrayMerge: opt.OverlayArrayMerge,
 	}
 	return app, nil
 }
@@ -214,7 +228,9 @@ func (app *App) AWSAccountID(ctx context.Context) string {
 	return app.callerIdentity.Account(ctx)
 }
 
-func loadDefinitionFile[T any](app *App, path string, defaults []string) (*T, error) {
+// loadDefinitionFile loads a definition file. When app.env is set, the overlay file is merged.
+// If overlayRequired is false, a missing overlay file is ignored.
+func loadDefinitionFile[T any](app *App, path string, defaults []string, overlayRequired bool) (*T, error) {
 	if path == "" {
 		p, err := findDefinitionFile("", defaults)
 		if err != nil {
@@ -223,6 +239,24 @@ func loadDefinitionFile[T any](app *App, path string, defaults []string) (*T, er
 		path = p
 	}
 
+	src, err := app.readDefinitionFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if app.env != "" {
+		if src, err = app.mergeOverlay(src, path, overlayRequired); err != nil {
+			return nil, err
+		}
+	}
+	var v T
+	if err := unmarshalJSON(src, &v, path); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return &v, nil
+}
+
+// readDefinitionFile reads a definition file and returns its JSON representation
+func (app *App) readDefinitionFile(path string) ([]byte, error) {
 	var (
 		src []byte
 		err error
@@ -247,21 +281,26 @@ func loadDefinitionFile[T any](app *App, path string, defaults []string) (*T, er
 		if err != nil {
 			return nil, err
 		}
+	case ".yaml", ".yml":
+		b, err := app.loader.ReadWithEnv(path)
+		if err != nil {
+			return nil, err
+		}
+		src, err = yamlToJSON(b)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		src, err = app.loader.ReadWithEnv(path)
 		if err != nil {
 			return nil, err
 		}
 	}
-	var v T
-	if err := unmarshalJSON(src, &v, path); err != nil {
-		return nil, fmt.Errorf("failed to load %s: %w", path, err)
-	}
-	return &v, nil
+	return src, nil
 }
 
 func (app *App) loadFunction(path string) (*Function, error) {
-	return loadDefinitionFile[Function](app, path, DefaultFunctionFilenames)
+	return loadDefinitionFile[Function](app, path, DefaultFunctionFilenames, true)
 }
 
 func newFunctionFrom(c *types.FunctionConfiguration, code *types.FunctionCodeLocation, tags Tags) *Function {
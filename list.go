@@ -1,44 +1,195 @@
 package lambroll
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/fujiwara/lambroll/wildcard"
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/sync/errgroup"
 )
 
 // ListOption represents options for List()
 type ListOption struct {
+	NamePattern string            `help:"filter by function name pattern (wildcard * and ? are supported)" default:""`
+	Tags        map[string]string `name:"tag" help:"filter by tag (key=value)"`
+	Runtime     []string          `help:"filter by runtime"`
+	Output      string            `help:"output format (table,json,jsonl,names)" default:"json" enum:"table,json,jsonl,names"`
+	Parallelism int               `help:"number of concurrent requests to list tags" default:"10"`
+}
+
+// needTags reports whether tags of functions are required
+func (opt *ListOption) needTags() bool {
+	return len(opt.Tags) > 0 || opt.Output == "json" || opt.Output == "jsonl"
+}
+
+func (opt *ListOption) match(c *types.FunctionConfiguration) bool {
+	if opt.NamePattern != "" && !wildcard.Match(opt.NamePattern, aws.ToString(c.FunctionName)) {
+		return false
+	}
+	if len(opt.Runtime) > 0 {
+		found := false
+		for _, r := range opt.Runtime {
+			if string(c.Runtime) == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (opt *ListOption) matchTags(tags Tags) bool {
+	for k, v := range opt.Tags {
+		if tv, ok := tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
+type listedFunction struct {
+	Configuration types.FunctionConfiguration
+	Tags          Tags
 }
 
 // List lists lambda functions
 func (app *App) List(ctx context.Context, opt *ListOption) error {
+	matched, err := app.listMatchedFunctions(ctx, opt)
+	if err != nil {
+		return err
+	}
+
+	return app.printListedFunctions(os.Stdout, matched, opt.Output)
+}
+
+// printListedFunctions prints the functions in the output format. secret values are masked in json and jsonl.
+func (app *App) printListedFunctions(w io.Writer, matched []*listedFunction, output string) error {
+	switch output {
+	case "names":
+		for _, fn := range matched {
+			fmt.Fprintln(w, aws.ToString(fn.Configuration.FunctionName))
+		}
+	case "table":
+		fmt.Fprint(w, listTable(matched))
+	case "jsonl":
+		for _, fn := range matched {
+			b, err := app.marshalJSONMasked(newFunctionFrom(&fn.Configuration, nil, fn.Tags))
+			if err != nil {
+				return fmt.Errorf("failed to marshal json: %w", err)
+			}
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, b); err != nil {
+				return fmt.Errorf("failed to compact json: %w", err)
+			}
+			fmt.Fprintln(w, buf.String())
+		}
+	default:
+		for _, fn := range matched {
+			b, err := app.marshalJSONMasked(newFunctionFrom(&fn.Configuration, nil, fn.Tags))
+			if err != nil {
+				return fmt.Errorf("failed to marshal json: %w", err)
+			}
+			w.Write(b)
+		}
+	}
+	return nil
+}
+
+func (app *App) listMatchedFunctions(ctx context.Context, opt *ListOption) ([]*listedFunction, error) {
+	fns, err := app.listFunctions(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.needTags() {
+		if err := app.fillFunctionTags(ctx, fns, opt.Parallelism); err != nil {
+			return nil, err
+		}
+	}
+	var matched []*listedFunction
+	for _, fn := range fns {
+		if opt.matchTags(fn.Tags) {
+			matched = append(matched, fn)
+		}
+	}
+	log.Printf("[debug] %d functions matched", len(matched))
+	return matched, nil
+}
+
+func (app *App) listFunctions(ctx context.Context, opt *ListOption) ([]*listedFunction, error) {
+	var fns []*listedFunction
 	var marker *string
 	for {
 		res, err := app.lambda.ListFunctions(ctx, &lambda.ListFunctionsInput{
 			MaxItems: aws.Int32(50),
+			Marker:   marker,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to ListFunctions: %w", err)
+			return nil, fmt.Errorf("failed to ListFunctions: %w", err)
 		}
 		for _, c := range res.Functions {
-			arn := app.functionArn(ctx, *c.FunctionName)
+			if opt.match(&c) {
+				fns = append(fns, &listedFunction{Configuration: c})
+			}
+		}
+		if marker = res.NextMarker; marker == nil {
+			break
+		}
+	}
+	return fns, nil
+}
+
+// fillFunctionTags lists tags of the functions concurrently
+func (app *App) fillFunctionTags(ctx context.Context, fns []*listedFunction, parallelism int) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(parallelism)
+	for _, fn := range fns {
+		fn := fn
+		eg.Go(func() error {
+			arn := aws.ToString(fn.Configuration.FunctionArn)
 			log.Printf("[debug] listing tags of %s", arn)
 			res, err := app.lambda.ListTags(ctx, &lambda.ListTagsInput{
 				Resource: aws.String(arn),
 			})
 			if err != nil {
-				return fmt.Errorf("failed to list tags: %w", err)
+				return fmt.Errorf("failed to list tags of %s: %w", arn, err)
 			}
-			b, _ := marshalJSON(newFunctionFrom(&c, nil, res.Tags))
-			os.Stdout.Write(b)
-		}
-		if marker = res.NextMarker; marker == nil {
-			break
-		}
+			fn.Tags = res.Tags
+			return nil
+		})
 	}
-	return nil
+	return eg.Wait()
+}
+
+func listTable(fns []*listedFunction) string {
+	buf := new(strings.Builder)
+	w := tablewriter.NewWriter(buf)
+	w.SetHeader([]string{"FunctionName", "Runtime", "PackageType", "MemorySize", "Last Modified"})
+	for _, fn := range fns {
+		c := fn.Configuration
+		w.Append([]string{
+			aws.ToString(c.FunctionName),
+			string(c.Runtime),
+			string(c.PackageType),
+			fmt.Sprintf("%d", aws.ToInt32(c.MemorySize)),
+			aws.ToString(c.LastModified),
+		})
+	}
+	w.Render()
+	return buf.String()
 }
@@ -0,0 +1,127 @@
+package lambroll_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestListServer(t *testing.T, tagCalls *int32) *httptest.Server {
+	pages := map[string][]string{
+		"":      {"app-api", "app-worker"},
+		"page2": {"batch-job", "app-cron"},
+	}
+	runtimes := map[string]string{
+		"app-api": "nodejs20.x", "app-worker": "python3.12", "batch-job": "nodejs20.x", "app-cron": "nodejs20.x",
+	}
+	tags := map[string]map[string]string{
+		"app-api":    {"Env": "prod"},
+		"app-worker": {"Env": "dev"},
+		"batch-job":  {"Env": "prod"},
+		"app-cron":   {"Env": "prod", "Team": "a"},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/2015-03-31/functions"):
+			marker := r.URL.Query().Get("Marker")
+			var fns []map[string]any
+			for _, name := range pages[marker] {
+				fns = append(fns, map[string]any{
+					"FunctionName": name,
+					"FunctionArn":  "arn:aws:lambda:ap-northeast-1:123456789012:function:" + name,
+					"Runtime":      runtimes[name],
+					"Environment":  map[string]any{"Variables": map[string]string{"API_TOKEN": "t0ken-" + name}},
+				})
+			}
+			res := map[string]any{"Functions": fns}
+			if marker == "" {
+				res["NextMarker"] = "page2"
+			}
+			json.NewEncoder(w).Encode(res)
+		case strings.HasPrefix(r.URL.Path, "/2017-03-31/tags/"):
+			atomic.AddInt32(tagCalls, 1)
+			arn := strings.TrimPrefix(r.URL.Path, "/2017-03-31/tags/")
+			name := arn[strings.LastIndex(arn, ":")+1:]
+			json.NewEncoder(w).Encode(map[string]any{"Tags": tags[name]})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "{}")
+		}
+	}))
+}
+
+func TestListFunctions(t *testing.T) {
+	var tagCalls int32
+	ts := newTestListServer(t, &tagCalls)
+	defer ts.Close()
+
+	ctx := context.Background()
+	app := newTestApp(t, ts)
+
+	tests := []struct {
+		name     string
+		opt      *lambroll.ListOption
+		expected []string
+		tagCalls int32
+	}{
+		{
+			name:     "all",
+			opt:      &lambroll.ListOption{Output: "names"},
+			expected: []string{"app-api", "app-worker", "batch-job", "app-cron"},
+		},
+		{
+			name:     "name pattern and runtime",
+			opt:      &lambroll.ListOption{Output: "names", NamePattern: "app-*", Runtime: []string{"nodejs20.x"}},
+			expected: []string{"app-api", "app-cron"},
+		},
+		{
+			name:     "tags",
+			opt:      &lambroll.ListOption{Output: "table", Tags: map[string]string{"Env": "prod"}, Parallelism: 2},
+			expected: []string{"app-api", "batch-job", "app-cron"},
+			tagCalls: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&tagCalls, 0)
+			names, err := app.ListFunctionNames(ctx, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.expected, names); diff != "" {
+				t.Errorf("unexpected functions (-want +got):\n%s", diff)
+			}
+			if n := atomic.LoadInt32(&tagCalls); n != tt.tagCalls {
+				t.Errorf("unexpected ListTags calls: %d, expected %d", n, tt.tagCalls)
+			}
+		})
+	}
+}
+
+func TestListFunctionsMasked(t *testing.T) {
+	var tagCalls int32
+	ts := newTestListServer(t, &tagCalls)
+	defer ts.Close()
+
+	ctx := context.Background()
+	app := newTestAppWithOption(t, ts, &lambroll.Option{MaskKeys: []string{"*_TOKEN"}})
+	for _, output := range []string{"json", "jsonl"} {
+		out, err := app.ListFunctionsOutput(ctx, &lambroll.ListOption{Output: output, NamePattern: "app-api"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out, "t0ken") || !strings.Contains(out, "**masked:") {
+			t.Errorf("secret values must be masked in %s output\n%s", output, out)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package lambroll
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// loadTest reports whether the load test mode is enabled
+func (opt *InvokeOption) loadTest() bool {
+	return opt.Count > 0 || opt.Rate > 0
+}
+
+func (opt *InvokeOption) validateLoadTest() error {
+	if opt.Async {
+		return fmt.Errorf("--async is not supported in the load test mode")
+	}
+	if opt.Rate > 0 && opt.Duration <= 0 {
+		return fmt.Errorf("--duration is required with --rate")
+	}
+	if opt.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be greater than 0")
+	}
+	if opt.MaxErrorRate < 0 || opt.MaxErrorRate > 100 {
+		return fmt.Errorf("--max-error-rate must be between 0 and 100")
+	}
+	return nil
+}
+
+var (
+	reportDurationRegexp     = regexp.MustCompile(`\tDuration: ([0-9.]+) ms`)
+	reportInitDurationRegexp = regexp.MustCompile(`Init Duration: ([0-9.]+) ms`)
+)
+
+// parseReportLine parses the REPORT line in the tail log.
+func parseReportLine(logs string) (duration float64, initDuration float64, found bool) {
+	for _, line := range strings.Split(logs, "\n") {
+		if !strings.HasPrefix(line, "REPORT ") {
+			continue
+		}
+		found = true
+		if m := reportDurationRegexp.FindStringSubmatch(line); m != nil {
+			duration, _ = strconv.ParseFloat(m[1], 64)
+		}
+		if m := reportInitDurationRegexp.FindStringSubmatch(line); m != nil {
+			initDuration, _ = strconv.ParseFloat(m[1], 64)
+		}
+	}
+	return
+}
+
+type loadTestResult struct {
+	Latency       time.Duration
+	Duration      float64 // duration in ms reported by REPORT line
+	InitDuration  float64 // init duration in ms (cold start)
+	Reported      bool
+	Throttled     bool
+	FunctionError string
+	Err           error
+}
+
+type loadTestReport struct {
+	Results []*loadTestResult
+	Elapsed time.Duration
+}
+
+func (app *App) invokeLoadTest(ctx context.Context, fn *Function, opt *InvokeOption, payloads [][]byte) error {
+	if err := opt.validateLoadTest(); err != nil {
+		return err
+	}
+	if len(payloads) == 0 {
+		return fmt.Errorf("no payloads to invoke")
+	}
+	if opt.Rate > 0 {
+		log.Printf("[info] invoking %s at %g req/s for %s (concurrency %d)", *fn.FunctionName, opt.Rate, opt.Duration, opt.Concurrency)
+	} else {
+		log.Printf("[info] invoking %s %d times (concurrency %d)", *fn.FunctionName, opt.Count, opt.Concurrency)
+	}
+
+	var (
+		mu      sync.Mutex
+		results []*loadTestResult
+		wg      sync.WaitGroup
+	)
+	sem := make(chan struct{}, opt.Concurrency)
+	// stopCtx stops scheduling new invocations. invocations in flight are not canceled by --fail-fast.
+	stopCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	invoke := func(i int) {
+		defer wg.Done()
+		defer func() { <-sem }()
+		r := app.invokeOnce(ctx, fn, opt.Qualifier, payloads[i%len(payloads)])
+		mu.Lock()
+		results = append(results, r)
+		mu.Unlock()
+		if opt.FailFast && r.failed() && stopCtx.Err() == nil {
+			log.Println("[warn] stop invoking by --fail-fast")
+			stop()
+		}
+	}
+
+	start := time.Now()
+	if opt.Rate > 0 {
+		interval := time.Duration(float64(time.Second) / opt.Rate)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		deadline := time.After(opt.Duration)
+	RATE:
+		for i := 0; opt.Count == 0 || i < opt.Count; i++ {
+			select {
+			case sem <- struct{}{}:
+			case <-stopCtx.Done():
+			}
+			if stopCtx.Err() != nil {
+				break RATE
+			}
+			wg.Add(1)
+			go invoke(i)
+			select {
+			case <-ticker.C:
+			case <-deadline:
+				break RATE
+			case <-stopCtx.Done():
+				break RATE
+			}
+		}
+	} else {
+		for i := 0; i < opt.Count; i++ {
+			select {
+			case sem <- struct{}{}:
+			case <-stopCtx.Done():
+			}
+			if stopCtx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go invoke(i)
+		}
+	}
+	wg.Wait()
+
+	report := &loadTestReport{Results: results, Elapsed: time.Since(start)}
+	report.Print(os.Stdout)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	failed := report.failed()
+	if failed == 0 {
+		return nil
+	}
+	rate := float64(failed) / float64(len(report.Results)) * 100
+	if rate <= opt.MaxErrorRate {
+		log.Printf("[warn] %d of %d invocations failed (%.1f%%), within --max-error-rate %g%%", failed, len(report.Results), rate, opt.MaxErrorRate)
+		return nil
+	}
+	return &ExitCodeError{
+		Code: 1,
+		Err:  fmt.Errorf("%d of %d invocations failed (%.1f%%)", failed, len(report.Results), rate),
+	}
+}
+
+// failed reports whether the invocation failed (errors, throttles and function errors)
+func (r *loadTestResult) failed() bool {
+	return r.Err != nil || r.FunctionError != ""
+}
+
+// failed returns the number of failed invocations
+func (r *loadTestReport) failed() int {
+	var n int
+	for _, res := range r.Results {
+		if res.failed() {
+			n++
+		}
+	}
+	return n
+}
+
+func (app *App) invokeOnce(ctx context.Context, fn *Function, qualifier *string, payload []byte) *loadTestResult {
+	in := &lambda.InvokeInput{
+		FunctionName:   fn.FunctionName,
+		InvocationType: types.InvocationTypeRequestResponse,
+		LogType:        types.LogTypeTail,
+		Payload:        payload,
+		Qualifier:      qualifier,
+	}
+	start := time.Now()
+	// retries hide throttles and distort latencies
+	res, err := app.lambda.Invoke(ctx, in, func(o *lambda.Options) {
+		o.RetryMaxAttempts = 1
+	})
+	r := &loadTestResult{Latency: time.Since(start)}
+	if err != nil {
+		var tmr *types.TooManyRequestsException
+		if errors.As(err, &tmr) {
+			r.Throttled = true
+		}
+		log.Println("[debug] failed to invoke function", err.Error())
+		r.Err = err
+		return r
+	}
+	r.FunctionError = aws.ToString(res.FunctionError)
+	if res.LogResult != nil {
+		b, _ := base64.StdEncoding.DecodeString(*res.LogResult)
+		r.Duration, r.InitDuration, r.Reported = parseReportLine(string(b))
+	}
+	return r
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func (r *loadTestReport) Print(w io.Writer) {
+	var latencies []time.Duration
+	var errs, throttles, functionErrors, reported, coldStarts int
+	var durations float64
+	for _, res := range r.Results {
+		switch {
+		case res.Throttled:
+			throttles++
+		case res.Err != nil:
+			errs++
+		default:
+			if res.FunctionError != "" {
+				functionErrors++
+			}
+			latencies = append(latencies, res.Latency)
+			if res.Reported {
+				reported++
+				durations += res.Duration
+				if res.InitDuration > 0 {
+					coldStarts++
+				}
+			}
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	total := len(r.Results)
+	fmt.Fprintf(w, "Requests:        %d in %s (%.2f req/s)\n", total, r.Elapsed.Round(time.Millisecond), float64(total)/r.Elapsed.Seconds())
+	fmt.Fprintf(w, "Succeeded:       %d\n", len(latencies)-functionErrors)
+	fmt.Fprintf(w, "Function errors: %d\n", functionErrors)
+	fmt.Fprintf(w, "Throttles:       %d\n", throttles)
+	fmt.Fprintf(w, "Other errors:    %d\n", errs)
+	if reported > 0 {
+		fmt.Fprintf(w, "Cold starts:     %d/%d (%.1f%%)\n", coldStarts, reported, float64(coldStarts)/float64(reported)*100)
+		fmt.Fprintf(w, "Duration avg:    %.2f ms (REPORT)\n", durations/float64(reported))
+	}
+	if len(latencies) == 0 {
+		return
+	}
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	fmt.Fprintln(w, "Latency:")
+	fmt.Fprintf(w, "  min: %s  avg: %s  max: %s\n",
+		latencies[0].Round(time.Microsecond*100),
+		(sum / time.Duration(len(latencies))).Round(time.Microsecond*100),
+		latencies[len(latencies)-1].Round(time.Microsecond*100),
+	)
+	fmt.Fprintf(w, "  p50: %s  p90: %s  p99: %s\n",
+		percentile(latencies, 50).Round(time.Microsecond*100),
+		percentile(latencies, 90).Round(time.Microsecond*100),
+		percentile(latencies, 99).Round(time.Microsecond*100),
+	)
+	fmt.Fprintln(w, "Histogram:")
+	fmt.Fprint(w, latencyHistogram(latencies, 10))
+}
+
+// latencyHistogram renders the histogram of sorted latencies with linear buckets.
+func latencyHistogram(sorted []time.Duration, buckets int) string {
+	min, max := sorted[0], sorted[len(sorted)-1]
+	width := (max - min) / time.Duration(buckets)
+	if width <= 0 {
+		return fmt.Sprintf("  %10s [%5d] %s\n", min.Round(time.Microsecond*100), len(sorted), strings.Repeat("■", 40))
+	}
+	counts := make([]int, buckets)
+	for _, l := range sorted {
+		i := int((l - min) / width)
+		if i >= buckets {
+			i = buckets - 1
+		}
+		counts[i]++
+	}
+	maxCount := 0
+	for _, c := range counts {
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+	var b strings.Builder
+	for i, c := range counts {
+		upper := min + width*time.Duration(i+1)
+		if i == buckets-1 {
+			upper = max
+		}
+		bar := strings.Repeat("■", c*40/maxCount)
+		fmt.Fprintf(&b, "  %10s [%5d] %s\n", upper.Round(time.Microsecond*100), c, bar)
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package lambroll_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/fujiwara/lambroll"
+)
+
+const testReportCold = "START RequestId: 1 Version: $LATEST\nEND RequestId: 1\nREPORT RequestId: 1\tDuration: 12.34 ms\tBilled Duration: 13 ms\tMemory Size: 128 MB\tMax Memory Used: 70 MB\tInit Duration: 150.50 ms\t\n"
+const testReportWarm = "START RequestId: 2 Version: $LATEST\nEND RequestId: 2\nREPORT RequestId: 2\tDuration: 2.00 ms\tBilled Duration: 2 ms\tMemory Size: 128 MB\tMax Memory Used: 70 MB\t\n"
+
+func TestParseReportLine(t *testing.T) {
+	d, initD, ok := lambroll.ParseReportLine(testReportCold)
+	if !ok || d != 12.34 || initD != 150.50 {
+		t.Errorf("unexpected cold start report: %v %v %v", d, initD, ok)
+	}
+	d, initD, ok = lambroll.ParseReportLine(testReportWarm)
+	if !ok || d != 2.00 || initD != 0 {
+		t.Errorf("unexpected warm start report: %v %v %v", d, initD, ok)
+	}
+	if _, _, ok := lambroll.ParseReportLine("hello\n"); ok {
+		t.Error("REPORT line must not be found")
+	}
+}
+
+func TestInvokeLoadTest(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/invocations") {
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("X-Amz-Log-Type") != "Tail" {
+			t.Errorf("LogType must be Tail")
+		}
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case n%5 == 0:
+			w.Header().Set("X-Amzn-Errortype", "TooManyRequestsException")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"Type":"User","message":"Rate Exceeded."}`)
+			return
+		case n == 1:
+			w.Header().Set("X-Amz-Log-Result", base64.StdEncoding.EncodeToString([]byte(testReportCold)))
+		default:
+			w.Header().Set("X-Amz-Log-Result", base64.StdEncoding.EncodeToString([]byte(testReportWarm)))
+		}
+		fmt.Fprint(w, `"ok"`)
+	}))
+	defer ts.Close()
+
+	fnPath := filepath.Join(t.TempDir(), "function.json")
+	if err := os.WriteFile(fnPath, []byte(`{"FunctionName":"hello"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	app := newTestAppWithOption(t, ts, &lambroll.Option{Function: fnPath})
+	err := app.Invoke(ctx, &lambroll.InvokeOption{
+		Payload:     aws.String(`{"foo":1} {"foo":2}`),
+		Count:       10,
+		Concurrency: 3,
+	})
+	var ee *lambroll.ExitCodeError
+	if !errors.As(err, &ee) || ee.Code != 1 || ee.Error() != "2 of 10 invocations failed (20.0%)" {
+		t.Errorf("throttled invocations must be reported as failures: %v", err)
+	}
+	// throttled requests must not be retried
+	if n := atomic.LoadInt32(&calls); n != 10 {
+		t.Errorf("unexpected invocations: %d", n)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if err := app.Invoke(ctx, &lambroll.InvokeOption{
+		Payload:      aws.String(`{}`),
+		Count:        10,
+		Concurrency:  3,
+		MaxErrorRate: 20,
+	}); err != nil {
+		t.Errorf("failures within --max-error-rate must not be an error: %v", err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	err = app.Invoke(ctx, &lambroll.InvokeOption{
+		Payload:     aws.String(`{}`),
+		Count:       20,
+		Concurrency: 1,
+		FailFast:    true,
+	})
+	if err == nil {
+		t.Error("--fail-fast must report the failure")
+	}
+	if n := atomic.LoadInt32(&calls); n != 5 {
+		t.Errorf("--fail-fast must stop at the first failure: %d invocations", n)
+	}
+
+	if err := app.Invoke(ctx, &lambroll.InvokeOption{
+		Payload:     aws.String(`{}`),
+		Rate:        10,
+		Concurrency: 1,
+	}); err == nil {
+		t.Error("--rate without --duration must be an error")
+	}
+}
@@ -0,0 +1,353 @@
+package lambroll
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultFunctionTimeout    = 3
+	defaultFunctionMemorySize = 128
+)
+
+// LocalOption represents options for Local()
+type LocalOption struct {
+	Invoke *LocalInvokeOption `cmd:"invoke" help:"invoke function locally with the built-in Runtime API emulator"`
+}
+
+// LocalInvokeOption represents options for LocalInvoke()
+type LocalInvokeOption struct {
+	Src         string  `help:"function zip archive or src dir" default:"."`
+	Command     string  `help:"command to run the runtime (executed by sh -c in the package directory). default is ./bootstrap for provided runtimes" default:""`
+	Payload     *string `help:"payload to invoke. if not specified, read from STDIN"`
+	PayloadFile string  `help:"file to read payloads. if not specified, read from STDIN" default:""`
+	FailFast    bool    `default:"false" help:"stop invoking at the first failure"`
+
+	ZipOption
+}
+
+// LocalInvoke invokes the function locally.
+// The package is extracted into a temporary directory and the runtime is started with the Runtime API emulator.
+func (app *App) LocalInvoke(ctx context.Context, opt *LocalInvokeOption) error {
+	if err := app.expandZipOption(&opt.ZipOption); err != nil {
+		return err
+	}
+	fn, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load function: %w", err)
+	}
+	if fn.PackageType == types.PackageTypeImage {
+		return fmt.Errorf("local invoke does not support PackageType=Image")
+	}
+	if len(fn.Layers) > 0 {
+		log.Printf("[warn] layers are not available in local invoke: %s", strings.Join(fn.Layers, ", "))
+	}
+
+	taskRoot, err := os.MkdirTemp("", "lambroll-local")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(taskRoot)
+	if err := extractPackage(opt.Src, opt.excludes, opt.KeepSymlink, taskRoot); err != nil {
+		return err
+	}
+
+	command := opt.Command
+	if command == "" {
+		if !strings.HasPrefix(string(fn.Runtime), "provided") {
+			return fmt.Errorf("--command is required for the runtime %s", fn.Runtime)
+		}
+		command = "./bootstrap"
+	}
+
+	api, err := newRuntimeAPI()
+	if err != nil {
+		return err
+	}
+	defer api.Close()
+
+	rt := &localRuntime{
+		api:     api,
+		dir:     taskRoot,
+		command: command,
+		env:     app.localRuntimeEnv(ctx, fn, taskRoot, api.Addr()),
+		timeout: time.Duration(aws.ToInt32(fn.Timeout)) * time.Second,
+		arn:     fmt.Sprintf("arn:aws:lambda:%s:000000000000:function:%s", app.awsConfig.Region, aws.ToString(fn.FunctionName)),
+	}
+	if rt.timeout == 0 {
+		rt.timeout = defaultFunctionTimeout * time.Second
+	}
+	defer rt.stop()
+
+	payloadSrc, closer, err := openPayloadSource(opt.Payload, opt.PayloadFile)
+	if err != nil {
+		return err
+	}
+	defer closer()
+	dec := json.NewDecoder(payloadSrc)
+	stdout := bufio.NewWriter(os.Stdout)
+	stderr := bufio.NewWriter(os.Stderr)
+	var results []*invokeResult
+	for {
+		var payload interface{}
+		if err := dec.Decode(&payload); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("failed to decode payload as JSON: %w", err)
+		}
+		b, _ := json.Marshal(payload)
+		result := &invokeResult{Index: len(results) + 1}
+		results = append(results, result)
+
+		res, err := rt.invoke(ctx, b)
+		if err != nil {
+			result.Err = err
+			log.Printf("[error] failed to invoke function: %s", err)
+		} else {
+			stdout.Write(res.Payload)
+			stdout.Write([]byte("\n"))
+			stdout.Flush()
+			if res.FunctionError != "" {
+				result.setFunctionError(res.FunctionError, res.Payload)
+				fmt.Fprintf(stderr, "FunctionError:%s %s\n", result.FunctionError, result.ErrorMessage)
+				stderr.Flush()
+			}
+		}
+		if opt.FailFast && result.failed() {
+			log.Println("[warn] stop invoking by --fail-fast")
+			break
+		}
+	}
+	return summarizeInvokeResults(results)
+}
+
+// localRuntimeEnv returns environment variables for the runtime process like the Lambda execution environment
+func (app *App) localRuntimeEnv(ctx context.Context, fn *Function, taskRoot, runtimeAPI string) []string {
+	name := aws.ToString(fn.FunctionName)
+	memorySize := aws.ToInt32(fn.MemorySize)
+	if memorySize == 0 {
+		memorySize = defaultFunctionMemorySize
+	}
+	env := map[string]string{
+		"AWS_LAMBDA_RUNTIME_API":          runtimeAPI,
+		"AWS_LAMBDA_FUNCTION_NAME":        name,
+		"AWS_LAMBDA_FUNCTION_VERSION":     versionLatest,
+		"AWS_LAMBDA_FUNCTION_MEMORY_SIZE": strconv.Itoa(int(memorySize)),
+		"AWS_LAMBDA_LOG_GROUP_NAME":       "/aws/lambda/" + name,
+		"AWS_LAMBDA_LOG_STREAM_NAME":      time.Now().Format("2006/01/02") + "/[$LATEST]local",
+		"AWS_REGION":                      app.awsConfig.Region,
+		"AWS_DEFAULT_REGION":              app.awsConfig.Region,
+		"LAMBDA_TASK_ROOT":                taskRoot,
+		"_HANDLER":                        aws.ToString(fn.Handler),
+	}
+	for _, k := range []string{"PATH", "LANG", "TMPDIR"} {
+		if v, ok := os.LookupEnv(k); ok {
+			env[k] = v
+		}
+	}
+	if creds, err := app.awsConfig.Credentials.Retrieve(ctx); err != nil {
+		log.Printf("[warn] failed to retrieve credentials for the function: %s", err)
+	} else {
+		env["AWS_ACCESS_KEY_ID"] = creds.AccessKeyID
+		env["AWS_SECRET_ACCESS_KEY"] = creds.SecretAccessKey
+		if creds.SessionToken != "" {
+			env["AWS_SESSION_TOKEN"] = creds.SessionToken
+		}
+	}
+	if fn.Environment != nil {
+		for k, v := range fn.Environment.Variables {
+			env[k] = v
+		}
+	}
+	vars := make([]string, 0, len(env))
+	for k, v := range env {
+		vars = append(vars, k+"="+v)
+	}
+	return vars
+}
+
+// localRuntime manages the runtime process. The process is started lazily and restarted after it exited or timed out.
+type localRuntime struct {
+	api     *runtimeAPI
+	dir     string
+	command string
+	env     []string
+	timeout time.Duration
+	arn     string
+
+	cmd    *exec.Cmd
+	exited chan error
+}
+
+func (rt *localRuntime) start() error {
+	log.Printf("[info] starting runtime: %s", rt.command)
+	rt.api.ResetInitError()
+	cmd := exec.Command("sh", "-c", rt.command)
+	cmd.Dir = rt.dir
+	cmd.Env = rt.env
+	// outputs of the function are logs
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	// run in a new process group to kill the runtime with its children
+	cmd.SysProcAttr = &unix.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start runtime: %w", err)
+	}
+	rt.cmd = cmd
+	rt.exited = make(chan error, 1)
+	go func() {
+		rt.exited <- cmd.Wait()
+	}()
+	return nil
+}
+
+func (rt *localRuntime) stop() {
+	if rt.cmd == nil {
+		return
+	}
+	unix.Kill(-rt.cmd.Process.Pid, unix.SIGKILL)
+	<-rt.exited
+	rt.cmd = nil
+}
+
+// cleanup kills the children of the exited runtime
+func (rt *localRuntime) cleanup() {
+	unix.Kill(-rt.cmd.Process.Pid, unix.SIGKILL)
+	rt.cmd = nil
+}
+
+func (rt *localRuntime) invoke(ctx context.Context, payload []byte) (*runtimeResponse, error) {
+	if rt.cmd != nil {
+		select {
+		case err := <-rt.exited:
+			log.Printf("[warn] runtime exited while idle: %v", err)
+			rt.cleanup()
+		default:
+		}
+	}
+	started := false
+	if rt.cmd == nil {
+		if err := rt.start(); err != nil {
+			return nil, err
+		}
+		started = true
+	}
+	start := time.Now()
+	inv := &runtimeInvocation{
+		RequestID:   newRequestID(),
+		Payload:     payload,
+		Deadline:    start.Add(rt.timeout),
+		FunctionArn: rt.arn,
+	}
+	log.Printf("[debug] invoking function locally RequestId: %s", inv.RequestID)
+	rt.api.Enqueue(inv)
+	timer := time.NewTimer(rt.timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-inv.done:
+		log.Printf("[info] RequestId: %s Duration: %.2f ms", inv.RequestID, float64(time.Since(start).Microseconds())/1000)
+		return res, nil
+	case res := <-rt.api.initErr:
+		rt.api.Forget(inv)
+		rt.stop()
+		return res, nil
+	case err := <-rt.exited:
+		received := !rt.api.Forget(inv)
+		rt.cleanup()
+		if !received && !started {
+			// the runtime started by the previous invocation exited before receiving this invocation. retry with a new runtime
+			log.Printf("[warn] runtime exited before receiving RequestId: %s, restarting: %v", inv.RequestID, err)
+			return rt.invoke(ctx, payload)
+		}
+		return newRuntimeErrorResponse("Runtime.ExitError",
+			fmt.Sprintf("RequestId: %s Error: Runtime exited with error: %v", inv.RequestID, err)), nil
+	case <-timer.C:
+		rt.api.Forget(inv)
+		rt.stop()
+		return newRuntimeErrorResponse("Sandbox.Timedout",
+			fmt.Sprintf("RequestId: %s Error: Task timed out after %.2f seconds", inv.RequestID, rt.timeout.Seconds())), nil
+	case <-ctx.Done():
+		rt.api.Forget(inv)
+		return nil, ctx.Err()
+	}
+}
+
+func newRuntimeErrorResponse(errorType, message string) *runtimeResponse {
+	b, _ := json.Marshal(functionErrorPayload{ErrorMessage: message, ErrorType: errorType})
+	return &runtimeResponse{Payload: b, FunctionError: "Unhandled", ErrorType: errorType}
+}
+
+// extractPackage extracts the function package (zip archive or src dir) into dest
+func extractPackage(src string, excludes []string, keepSymlink bool, dest string) error {
+	zipfile, info, err := prepareZipfile(src, excludes, keepSymlink)
+	if err != nil {
+		return err
+	}
+	defer zipfile.Close()
+	r, err := zip.NewReader(zipfile, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to read zip archive: %w", err)
+	}
+	for _, f := range r.File {
+		if err := extractZipFile(f, dest); err != nil {
+			return err
+		}
+	}
+	log.Printf("[debug] extracted %d files into %s", len(r.File), dest)
+	return nil
+}
+
+func extractZipFile(f *zip.File, dest string) error {
+	path := filepath.Join(dest, filepath.FromSlash(f.Name))
+	if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return fmt.Errorf("invalid file path in zip archive: %s", f.Name)
+	}
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(path, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s in zip archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+	if f.Mode()&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(rc)
+		if err != nil {
+			return fmt.Errorf("failed to read symlink %s: %w", f.Name, err)
+		}
+		return os.Symlink(string(target), path)
+	}
+	mode := f.Mode().Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+	w, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := io.Copy(w, rc); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+	}
+	return w.Close()
+}
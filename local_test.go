@@ -0,0 +1,133 @@
+package lambroll_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/fujiwara/lambroll"
+)
+
+func TestMain(m *testing.M) {
+	// the test binary works as a runtime for TestLocalInvoke
+	if os.Getenv("LAMBROLL_TEST_RUNTIME") != "" {
+		testRuntime()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func testRuntime() {
+	api := "http://" + os.Getenv("AWS_LAMBDA_RUNTIME_API") + "/2018-06-01/runtime/invocation/"
+	for {
+		res, err := http.Get(api + "next")
+		if err != nil {
+			os.Exit(2)
+		}
+		id := res.Header.Get("Lambda-Runtime-Aws-Request-Id")
+		var payload map[string]any
+		json.NewDecoder(res.Body).Decode(&payload)
+		res.Body.Close()
+		fmt.Fprintf(os.Stderr, "START RequestId: %s\n", id)
+
+		switch {
+		case payload["exit"] != nil:
+			os.Exit(1)
+		case payload["sleep"] != nil:
+			time.Sleep(10 * time.Second)
+		}
+		if payload["fail"] != nil || os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "hello" || os.Getenv("FOO") != "bar" {
+			http.Post(api+id+"/error", "application/json",
+				bytes.NewReader([]byte(`{"errorMessage":"failed","errorType":"TestError"}`)))
+			continue
+		}
+		b, _ := json.Marshal(payload)
+		http.Post(api+id+"/response", "application/json", bytes.NewReader(b))
+		if payload["exitAfter"] != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+func TestLocalInvoke(t *testing.T) {
+	dir := t.TempDir()
+	fnPath := filepath.Join(dir, "function.json")
+	if err := os.WriteFile(fnPath, []byte(`{
+  "FunctionName": "hello",
+  "Runtime": "provided.al2023",
+  "Handler": "bootstrap",
+  "Timeout": 1,
+  "Environment": {"Variables": {"FOO": "bar", "LAMBROLL_TEST_RUNTIME": "1"}}
+}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "src")
+	os.Mkdir(src, 0755)
+	if err := os.WriteFile(filepath.Join(src, "index.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	app := newTestAppWithOption(t, nil, &lambroll.Option{Function: fnPath})
+
+	tests := []struct {
+		name     string
+		payload  string
+		failFast bool
+		errMsg   string
+	}{
+		{name: "ok", payload: `{"a":1} {"a":2}`},
+		{name: "function error", payload: `{"fail":1} {"a":2}`, errMsg: "1 of 2 invocations failed"},
+		{name: "fail fast", payload: `{"fail":1} {"a":2}`, failFast: true, errMsg: "1 of 1 invocations failed"},
+		{name: "timeout", payload: `{"sleep":1} {"a":2}`, errMsg: "1 of 2 invocations failed"},
+		{name: "exit", payload: `{"exit":1} {"a":2}`, errMsg: "1 of 2 invocations failed"},
+		{name: "exit while idle", payload: `{"exitAfter":1} {"a":2} {"exitAfter":1} {"a":3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := app.LocalInvoke(ctx, &lambroll.LocalInvokeOption{
+				Src:      src,
+				Command:  "'" + exe + "'",
+				Payload:  aws.String(tt.payload),
+				FailFast: tt.failFast,
+			})
+			if tt.errMsg == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if tt.errMsg != "" && (err == nil || err.Error() != tt.errMsg) {
+				t.Errorf("unexpected error: %v, expected %s", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestRuntimeAPIInitErrors(t *testing.T) {
+	api, addr, err := lambroll.NewRuntimeAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.Close()
+	client := &http.Client{Timeout: time.Second}
+	// the runtime must not be blocked by init errors which nobody waits for
+	for i := 0; i < 3; i++ {
+		res, err := client.Post("http://"+addr+"/2018-06-01/runtime/init/error", "application/json",
+			bytes.NewReader([]byte(`{"errorMessage":"failed","errorType":"InitError"}`)))
+		if err != nil {
+			t.Fatalf("init error %d: %s", i, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusAccepted {
+			t.Errorf("unexpected status %d", res.StatusCode)
+		}
+	}
+}
@@ -1,13 +1,23 @@
 package lambroll
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
-	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwltypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/fatih/color"
 )
 
 type LogsOption struct {
@@ -15,8 +25,18 @@ type LogsOption struct {
 	Follow        *bool   `help:"follow new logs" default:"false"`
 	Format        *string `help:"The format to display the logs" default:"detailed" enum:"detailed,short,json"`
 	FilterPattern *string `help:"The filter pattern to use"`
+	Level         string  `help:"minimum log level to display for JSON format logs (trace,debug,info,warn,error,fatal)" default:"" enum:",trace,debug,info,warn,error,fatal"`
+	RequestID     string  `help:"display logs of the request ID only" default:""`
+	Query         string  `help:"jq query to filter and transform JSON format logs. events are displayed when the query returns neither null nor false" default:""`
 }
 
+// logsPollInterval is the interval to poll new logs in the follow mode
+var logsPollInterval = 5 * time.Second
+
+// logsLookback is the duration to look back from the latest event in the follow mode,
+// to print the events ingested late with earlier timestamps (e.g. from other log streams)
+var logsLookback = 30 * time.Second
+
 func (app *App) Logs(ctx context.Context, opt *LogsOption) error {
 	fn, err := app.loadFunction(app.functionFilePath)
 	if err != nil {
@@ -24,33 +44,168 @@ func (app *App) Logs(ctx context.Context, opt *LogsOption) error {
 	}
 
 	logGroup := resolveLogGroup(fn)
-	command := []string{
-		"aws",
-		"--profile", app.profile,
-		"--region", app.awsConfig.Region,
-		"logs",
-		"tail",
-		logGroup,
-	}
+	start := time.Now().Add(-10 * time.Minute)
 	if opt.Since != nil {
-		command = append(command, "--since", *opt.Since)
-	}
-	if opt.Follow != nil && *opt.Follow {
-		command = append(command, "--follow")
+		if start, err = parseSince(*opt.Since, time.Now()); err != nil {
+			return err
+		}
 	}
+	format := "detailed"
 	if opt.Format != nil {
-		command = append(command, "--format", *opt.Format)
-	}
-	if opt.FilterPattern != nil && *opt.FilterPattern != "" {
-		command = append(command, "--filter-pattern", *opt.FilterPattern)
+		format = *opt.Format
 	}
-	bin, err := exec.LookPath(command[0])
+	filter, err := newLogsFilter(opt.Level, opt.RequestID, opt.Query)
 	if err != nil {
 		return err
 	}
-	log.Println("[debug] invoking command", strings.Join(command, " "))
-	if err := syscall.Exec(bin, command, os.Environ()); err != nil {
-		return fmt.Errorf("failed to invoke aws logs tail: %w", err)
+	if filter.structured() && (fn.LoggingConfig == nil || fn.LoggingConfig.LogFormat != types.LogFormatJson) {
+		log.Println("[warn] --level and --query require LoggingConfig.LogFormat JSON. logs in text format are not displayed")
+	}
+	filterPattern := aws.ToString(opt.FilterPattern)
+	if filterPattern == "" && opt.RequestID != "" {
+		// narrow down events at the server side. the filter matches both text and JSON formats
+		filterPattern = fmt.Sprintf("%q", opt.RequestID)
+	}
+	t := app.newLogsTailer(logGroup, format, filterPattern, os.Stdout)
+	t.filter = filter
+	log.Printf("[debug] tailing logs of %s since %s", logGroup, start.Format(time.RFC3339))
+
+	startTime := start.UnixMilli()
+	for {
+		if startTime, err = t.tail(ctx, startTime); err != nil {
+			return err
+		}
+		if opt.Follow == nil || !*opt.Follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logsPollInterval):
+		}
+	}
+}
+
+// logsTailer prints log events of the log group by FilterLogEvents
+type logsTailer struct {
+	client        *cloudwatchlogs.Client
+	logGroup      string
+	filterPattern *string
+	format        string
+	filter        *logsFilter
+	w             io.Writer
+
+	// the latest timestamp of the printed events
+	latest int64
+	// event IDs already printed in the lookback window => timestamp, to avoid duplicates in the follow mode
+	seen map[string]int64
+}
+
+func (app *App) newLogsTailer(logGroup, format, filterPattern string, w io.Writer) *logsTailer {
+	t := &logsTailer{
+		client:   cloudwatchlogs.NewFromConfig(app.awsConfig),
+		logGroup: logGroup,
+		format:   format,
+		w:        w,
+		seen:     make(map[string]int64),
+	}
+	if filterPattern != "" {
+		t.filterPattern = aws.String(filterPattern)
+	}
+	return t
+}
+
+// tail prints log events since startTime and returns the start time for the next call.
+// The next start time looks back logsLookback from the latest event, but not before startTime.
+func (t *logsTailer) tail(ctx context.Context, startTime int64) (int64, error) {
+	var nextToken *string
+	for {
+		res, err := t.client.FilterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName:  aws.String(t.logGroup),
+			StartTime:     aws.Int64(startTime),
+			FilterPattern: t.filterPattern,
+			NextToken:     nextToken,
+			Interleaved:   aws.Bool(true),
+		})
+		if err != nil {
+			return startTime, fmt.Errorf("failed to filter log events of %s: %w", t.logGroup, err)
+		}
+		for _, ev := range res.Events {
+			id := aws.ToString(ev.EventId)
+			if _, ok := t.seen[id]; ok {
+				continue
+			}
+			ts := aws.ToInt64(ev.Timestamp)
+			t.seen[id] = ts
+			if ts > t.latest {
+				t.latest = ts
+			}
+			if t.filter != nil {
+				msg, ok, err := t.filter.apply(strings.TrimRight(aws.ToString(ev.Message), "\n"))
+				if err != nil {
+					// a query may fail on some events only (e.g. a field of another type)
+					log.Printf("[warn] skipped the log event %s: %s", id, err)
+					continue
+				}
+				if !ok {
+					continue
+				}
+				ev.Message = aws.String(msg)
+			}
+			fmt.Fprintln(t.w, formatLogEvent(ev, t.format))
+		}
+		if nextToken = res.NextToken; nextToken == nil {
+			break
+		}
+	}
+
+	next := max(startTime, t.latest-logsLookback.Milliseconds())
+	for id, ts := range t.seen {
+		if ts < next {
+			// never returned again
+			delete(t.seen, id)
+		}
+	}
+	return next, nil
+}
+
+// formatLogEvent formats the event as same as aws logs tail --format
+func formatLogEvent(ev cwltypes.FilteredLogEvent, format string) string {
+	ts := time.UnixMilli(aws.ToInt64(ev.Timestamp)).UTC()
+	msg := strings.TrimRight(aws.ToString(ev.Message), "\n")
+	switch format {
+	case "short":
+		return color.GreenString(ts.Format("2006-01-02T15:04:05")) + " " + msg
+	case "json":
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(msg), "", "  "); err == nil {
+			msg = buf.String()
+		}
+	}
+	return color.GreenString(ts.Format("2006-01-02T15:04:05.000000-07:00")) + " " +
+		color.CyanString(aws.ToString(ev.LogStreamName)) + " " + msg
+}
+
+var sinceRegexp = regexp.MustCompile(`^(\d+)([smhdw])$`)
+
+// parseSince parses the time to begin displaying logs.
+// It accepts a relative duration (e.g. 10m, 2h, 1d, 1w) or an absolute timestamp (e.g. 2024-01-02T03:04:05Z).
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if m := sinceRegexp.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]time.Duration{
+			"s": time.Second,
+			"m": time.Minute,
+			"h": time.Hour,
+			"d": 24 * time.Hour,
+			"w": 7 * 24 * time.Hour,
+		}[m[2]]
+		return now.Add(-time.Duration(n) * unit), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
 	}
-	return nil
+	return time.Time{}, fmt.Errorf("invalid --since %q. use a relative time (e.g. 10m, 2h, 1d) or a timestamp (e.g. 2024-01-02T03:04:05Z)", s)
 }
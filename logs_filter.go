@@ -0,0 +1,125 @@
+package lambroll
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// logLevels represents the order of log levels of the JSON format logs
+var logLevels = map[string]int{
+	"TRACE":    0,
+	"DEBUG":    1,
+	"INFO":     2,
+	"WARN":     3,
+	"WARNING":  3,
+	"ERROR":    4,
+	"FATAL":    5,
+	"CRITICAL": 5,
+}
+
+// logsFilter filters log events by the level, the request ID and the jq query
+type logsFilter struct {
+	level     int // minimum level. -1 means no level filter
+	requestID string
+	query     *gojq.Code
+}
+
+func newLogsFilter(level, requestID, query string) (*logsFilter, error) {
+	f := &logsFilter{level: -1, requestID: requestID}
+	if level != "" {
+		l, ok := logLevels[strings.ToUpper(level)]
+		if !ok {
+			return nil, fmt.Errorf("invalid log level: %s", level)
+		}
+		f.level = l
+	}
+	if query != "" {
+		p, err := gojq.Parse(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse query: %s %w", query, err)
+		}
+		code, err := gojq.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile query: %s %w", query, err)
+		}
+		f.query = code
+	}
+	return f, nil
+}
+
+// structured reports whether the filter requires the JSON format logs
+func (f *logsFilter) structured() bool {
+	return f.level >= 0 || f.query != nil
+}
+
+// apply applies the filter to the message.
+// It returns the message to print and whether the message matched.
+func (f *logsFilter) apply(msg string) (string, bool, error) {
+	var v map[string]any
+	if err := json.Unmarshal([]byte(msg), &v); err != nil {
+		// not a JSON format log
+		if f.structured() {
+			return "", false, nil
+		}
+		return msg, f.requestID == "" || strings.Contains(msg, f.requestID), nil
+	}
+	if f.requestID != "" && logRequestID(v) != f.requestID {
+		return "", false, nil
+	}
+	if f.level >= 0 {
+		level, ok := v["level"].(string)
+		if !ok {
+			return "", false, nil
+		}
+		if l, ok := logLevels[strings.ToUpper(level)]; !ok || l < f.level {
+			return "", false, nil
+		}
+	}
+	if f.query == nil {
+		return msg, true, nil
+	}
+
+	var outs []string
+	iter := f.query.Run(v)
+	for {
+		r, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := r.(error); ok {
+			return "", false, fmt.Errorf("failed to run query: %w", err)
+		}
+		if r == nil || r == false {
+			continue
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(r); err != nil {
+			return "", false, fmt.Errorf("failed to encode query result: %w", err)
+		}
+		outs = append(outs, strings.TrimRight(buf.String(), "\n"))
+	}
+	if len(outs) == 0 {
+		return "", false, nil
+	}
+	return strings.Join(outs, "\n"), true, nil
+}
+
+// logRequestID returns the request ID of the JSON format log.
+// Application logs have "requestId" and platform events have "record.requestId".
+func logRequestID(v map[string]any) string {
+	if id, ok := v["requestId"].(string); ok {
+		return id
+	}
+	if record, ok := v["record"].(map[string]any); ok {
+		if id, ok := record["requestId"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package lambroll
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwltypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/olekukonko/tablewriter"
+)
+
+// LogsCommandOption represents subcommands of logs
+type LogsCommandOption struct {
+	Tail  *LogsOption      `cmd:"tail" default:"withargs" help:"show logs of function (default)"`
+	Query *LogsQueryOption `cmd:"query" help:"run CloudWatch Logs Insights query for logs of function"`
+}
+
+// LogsQueryOption represents options for LogsQuery()
+type LogsQueryOption struct {
+	Query  string `arg:"" optional:"" help:"query string of CloudWatch Logs Insights"`
+	Preset string `help:"run the preset query (cold-starts,max-memory,slowest)" default:"" enum:",cold-starts,max-memory,slowest"`
+	Since  string `help:"From what time to begin the query" default:"1h"`
+	Until  string `help:"To what time to end the query (default now)" default:""`
+	Limit  int32  `help:"maximum number of results" default:"100"`
+	Output string `help:"output format (table,json,csv)" default:"table" enum:"table,json,csv"`
+}
+
+// logsQueryPresets defines canned queries for the REPORT lines of Lambda functions
+var logsQueryPresets = map[string]string{
+	"cold-starts": `filter @type = "REPORT" and ispresent(@initDuration)
+| stats count(*) as coldStarts, avg(@initDuration) as avgInitDuration, max(@initDuration) as maxInitDuration by bin(1h) as hour
+| sort hour desc`,
+	"max-memory": `filter @type = "REPORT"
+| stats max(@maxMemoryUsed / 1000 / 1000) as maxMemoryUsedMB, avg(@maxMemoryUsed / 1000 / 1000) as avgMemoryUsedMB, max(@memorySize / 1000 / 1000) as memorySizeMB, count(*) as invocations`,
+	"slowest": `filter @type = "REPORT"
+| fields @timestamp, @requestId, @duration, @billedDuration, @initDuration, @maxMemoryUsed / 1000 / 1000 as maxMemoryUsedMB
+| sort @duration desc`,
+}
+
+// logsQueryPollInterval is the interval to poll the query results
+var logsQueryPollInterval = time.Second
+
+// logsQueryResult represents results of a Logs Insights query
+type logsQueryResult struct {
+	Columns []string
+	Rows    []map[string]string
+}
+
+// LogsQuery runs a CloudWatch Logs Insights query for the log group of the function
+func (app *App) LogsQuery(ctx context.Context, opt *LogsQueryOption) error {
+	query := opt.Query
+	if opt.Preset != "" {
+		if query != "" {
+			return fmt.Errorf("query and --preset cannot be specified at the same time")
+		}
+		query = logsQueryPresets[opt.Preset]
+	}
+	if query == "" {
+		return fmt.Errorf("query or --preset is required")
+	}
+	now := time.Now()
+	start, err := parseSince(opt.Since, now)
+	if err != nil {
+		return err
+	}
+	end := now
+	if opt.Until != "" {
+		if end, err = parseSince(opt.Until, now); err != nil {
+			return err
+		}
+	}
+
+	fn, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load function: %w", err)
+	}
+	logGroup := resolveLogGroup(fn)
+	res, err := app.runLogsQuery(ctx, logGroup, query, start, end, opt.Limit)
+	if err != nil {
+		return err
+	}
+	switch opt.Output {
+	case "json":
+		b, err := res.JSON()
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(b)
+	case "csv":
+		s, err := res.CSV()
+		if err != nil {
+			return err
+		}
+		fmt.Print(s)
+	default:
+		fmt.Print(res.Table())
+	}
+	return nil
+}
+
+func (app *App) runLogsQuery(ctx context.Context, logGroup, query string, start, end time.Time, limit int32) (*logsQueryResult, error) {
+	svc := cloudwatchlogs.NewFromConfig(app.awsConfig)
+	in := &cloudwatchlogs.StartQueryInput{
+		LogGroupName: aws.String(logGroup),
+		QueryString:  aws.String(query),
+		StartTime:    aws.Int64(start.Unix()),
+		EndTime:      aws.Int64(end.Unix()),
+	}
+	if limit > 0 {
+		in.Limit = aws.Int32(limit)
+	}
+	log.Printf("[debug] starting query for %s from %s to %s: %s", logGroup, start.Format(time.RFC3339), end.Format(time.RFC3339), query)
+	sq, err := svc.StartQuery(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start query: %w", err)
+	}
+	queryID := aws.ToString(sq.QueryId)
+	log.Printf("[info] query %s started", queryID)
+
+	// stop the query on any error (including interruption) not to leave it running
+	finished := false
+	defer func() {
+		if finished {
+			return
+		}
+		log.Printf("[info] stopping query %s", queryID)
+		if _, err := svc.StopQuery(context.Background(), &cloudwatchlogs.StopQueryInput{QueryId: aws.String(queryID)}); err != nil {
+			log.Printf("[warn] failed to stop query %s: %s", queryID, err)
+		}
+	}()
+
+	for {
+		res, err := svc.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
+			QueryId: aws.String(queryID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get query results: %w", err)
+		}
+		switch res.Status {
+		case cwltypes.QueryStatusComplete:
+			finished = true
+			if s := res.Statistics; s != nil {
+				log.Printf("[info] query completed. %.0f records matched, %.0f records scanned", s.RecordsMatched, s.RecordsScanned)
+			}
+			return newLogsQueryResult(res.Results), nil
+		case cwltypes.QueryStatusFailed, cwltypes.QueryStatusCancelled, cwltypes.QueryStatusTimeout, cwltypes.QueryStatusUnknown:
+			finished = true
+			return nil, fmt.Errorf("query %s finished with status %s", queryID, res.Status)
+		}
+		log.Printf("[debug] query %s is %s", queryID, res.Status)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(logsQueryPollInterval):
+		}
+	}
+}
+
+func newLogsQueryResult(results [][]cwltypes.ResultField) *logsQueryResult {
+	r := &logsQueryResult{}
+	seen := make(map[string]bool)
+	for _, fields := range results {
+		row := make(map[string]string, len(fields))
+		for _, f := range fields {
+			name := aws.ToString(f.Field)
+			if name == "@ptr" {
+				continue
+			}
+			if !seen[name] {
+				seen[name] = true
+				r.Columns = append(r.Columns, name)
+			}
+			row[name] = aws.ToString(f.Value)
+		}
+		r.Rows = append(r.Rows, row)
+	}
+	return r
+}
+
+func (r *logsQueryResult) Table() string {
+	buf := new(strings.Builder)
+	w := tablewriter.NewWriter(buf)
+	w.SetAutoWrapText(false)
+	w.SetAutoFormatHeaders(false)
+	w.SetHeader(r.Columns)
+	for _, row := range r.Rows {
+		w.Append(r.values(row))
+	}
+	w.Render()
+	return buf.String()
+}
+
+func (r *logsQueryResult) CSV() (string, error) {
+	buf := new(strings.Builder)
+	w := csv.NewWriter(buf)
+	w.Write(r.Columns)
+	for _, row := range r.Rows {
+		w.Write(r.values(row))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("failed to write csv: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func (r *logsQueryResult) JSON() ([]byte, error) {
+	rows := r.Rows
+	if rows == nil {
+		rows = []map[string]string{}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rows); err != nil {
+		return nil, fmt.Errorf("failed to encode results: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *logsQueryResult) values(row map[string]string) []string {
+	values := make([]string, 0, len(r.Columns))
+	for _, c := range r.Columns {
+		values = append(values, row[c])
+	}
+	return values
+}
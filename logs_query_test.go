@@ -0,0 +1,99 @@
+package lambroll_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunLogsQuery(t *testing.T) {
+	var started map[string]any
+	var stopped []string
+	polls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "Logs_20140328.StartQuery":
+			json.NewDecoder(r.Body).Decode(&started)
+			if started["queryString"] == "broken" {
+				w.Write([]byte(`{"queryId":"q-2"}`))
+				return
+			}
+			w.Write([]byte(`{"queryId":"q-1"}`))
+		case "Logs_20140328.StopQuery":
+			var in map[string]any
+			json.NewDecoder(r.Body).Decode(&in)
+			stopped = append(stopped, in["queryId"].(string))
+			w.Write([]byte(`{"success":true}`))
+		case "Logs_20140328.GetQueryResults":
+			var in map[string]any
+			json.NewDecoder(r.Body).Decode(&in)
+			if in["queryId"] == "q-2" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"InvalidParameterException","message":"invalid"}`))
+				return
+			}
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"status":"Running","results":[]}`))
+				return
+			}
+			w.Write([]byte(`{"status":"Complete","statistics":{"recordsMatched":2,"recordsScanned":10},"results":[
+[{"field":"@timestamp","value":"2024-01-02 03:04:05.000"},{"field":"@duration","value":"120.5"},{"field":"@ptr","value":"xxx"}],
+[{"field":"@timestamp","value":"2024-01-02 03:04:06.000"},{"field":"@duration","value":"80.1"},{"field":"@initDuration","value":"200.3"},{"field":"@ptr","value":"yyy"}]
+]}`))
+		default:
+			t.Errorf("unexpected target %s", target)
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	app := newTestApp(t, ts)
+	end := time.Date(2024, 1, 2, 4, 0, 0, 0, time.UTC)
+	res, err := app.RunLogsQuery(ctx, "/aws/lambda/hello", "fields @timestamp, @duration", end.Add(-time.Hour), end, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started["logGroupName"] != "/aws/lambda/hello" || started["limit"] != float64(10) ||
+		started["startTime"] != float64(end.Add(-time.Hour).Unix()) || started["endTime"] != float64(end.Unix()) {
+		t.Errorf("unexpected StartQuery input: %v", started)
+	}
+	if polls != 2 {
+		t.Errorf("unexpected polls: %d", polls)
+	}
+	if len(stopped) != 0 {
+		t.Errorf("completed query must not be stopped: %v", stopped)
+	}
+	if _, err := app.RunLogsQuery(ctx, "/aws/lambda/hello", "broken", end.Add(-time.Hour), end, 10); err == nil {
+		t.Error("expected error for failure of GetQueryResults")
+	}
+	if diff := cmp.Diff([]string{"q-2"}, stopped); diff != "" {
+		t.Errorf("the query must be stopped on errors: %s", diff)
+	}
+
+	expected := &lambroll.LogsQueryResult{
+		Columns: []string{"@timestamp", "@duration", "@initDuration"},
+		Rows: []map[string]string{
+			{"@timestamp": "2024-01-02 03:04:05.000", "@duration": "120.5"},
+			{"@timestamp": "2024-01-02 03:04:06.000", "@duration": "80.1", "@initDuration": "200.3"},
+		},
+	}
+	if diff := cmp.Diff(expected, res); diff != "" {
+		t.Errorf("unexpected result: %s", diff)
+	}
+	csv, err := res.CSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCSV := "@timestamp,@duration,@initDuration\n2024-01-02 03:04:05.000,120.5,\n2024-01-02 03:04:06.000,80.1,200.3\n"
+	if csv != expectedCSV {
+		t.Errorf("unexpected csv:\n%s", csv)
+	}
+}
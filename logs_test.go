@@ -0,0 +1,226 @@
+package lambroll_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fujiwara/lambroll"
+)
+
+type testLogEvent struct {
+	EventId       string `json:"eventId"`
+	LogStreamName string `json:"logStreamName"`
+	Message       string `json:"message"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+func TestLogsTail(t *testing.T) {
+	events := []testLogEvent{
+		{EventId: "1", LogStreamName: "s1", Message: "START RequestId: a\n", Timestamp: 1704164645000},
+		{EventId: "2", LogStreamName: "s1", Message: `{"level":"INFO","message":"hello"}` + "\n", Timestamp: 1704164645123},
+		{EventId: "3", LogStreamName: "s1", Message: "END RequestId: a\n", Timestamp: 1704164646000},
+	}
+	var requests []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "Logs_20140328.FilterLogEvents" {
+			t.Errorf("unexpected target %s", target)
+		}
+		var in map[string]any
+		json.NewDecoder(r.Body).Decode(&in)
+		requests = append(requests, in)
+		start := int64(in["startTime"].(float64))
+		var evs []testLogEvent
+		for _, ev := range events {
+			if ev.Timestamp >= start {
+				evs = append(evs, ev)
+			}
+		}
+		out := map[string]any{}
+		// returns the events one by one to test pagination
+		if token, _ := in["nextToken"].(string); token == "" && len(evs) > 1 {
+			out["events"] = evs[:1]
+			out["nextToken"] = "next"
+		} else if len(evs) > 1 {
+			out["events"] = evs[1:]
+		} else {
+			out["events"] = evs
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	app := newTestApp(t, ts)
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	var buf bytes.Buffer
+	tailer := app.NewLogsTailer("/aws/lambda/hello", "detailed", "RequestId", &buf)
+	next, err := tailer.Tail(ctx, 1704164640000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `2024-01-02T03:04:05.000000+00:00 s1 START RequestId: a
+2024-01-02T03:04:05.123000+00:00 s1 {"level":"INFO","message":"hello"}
+2024-01-02T03:04:06.000000+00:00 s1 END RequestId: a
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+	// looks back 30s from the latest event, but not before the start time
+	if next != 1704164640000 {
+		t.Errorf("unexpected next start time %d", next)
+	}
+	if p := requests[0]["filterPattern"]; p != "RequestId" {
+		t.Errorf("unexpected filterPattern %v", p)
+	}
+	if requests[1]["startTime"] != requests[0]["startTime"] {
+		t.Errorf("startTime must not be changed in pagination: %v", requests[1]["startTime"])
+	}
+
+	// the events already printed are not printed again
+	buf.Reset()
+	if _, err := tailer.Tail(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected duplicated output: %s", buf.String())
+	}
+
+	// an event ingested late with an earlier timestamp than the latest one is printed
+	events = append(events, testLogEvent{EventId: "late", LogStreamName: "s2", Message: "late\n", Timestamp: 1704164645500})
+	buf.Reset()
+	if _, err := tailer.Tail(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "2024-01-02T03:04:05.500000+00:00 s2 late\n"; buf.String() != expected {
+		t.Errorf("unexpected output of the late event: %q", buf.String())
+	}
+	events = events[:len(events)-1]
+
+	buf.Reset()
+	if _, err := app.NewLogsTailer("/aws/lambda/hello", "short", "", &buf).Tail(ctx, 1704164645100); err != nil {
+		t.Fatal(err)
+	}
+	expected = `2024-01-02T03:04:05 {"level":"INFO","message":"hello"}
+2024-01-02T03:04:06 END RequestId: a
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected short output:\n%s", buf.String())
+	}
+
+	// a query error on an event does not abort tailing
+	events = append(events,
+		testLogEvent{EventId: "4", LogStreamName: "s1", Message: `{"level":1,"message":"invalid"}` + "\n", Timestamp: 1704164647000},
+		testLogEvent{EventId: "5", LogStreamName: "s1", Message: `{"level":"INFO","message":"world"}` + "\n", Timestamp: 1704164648000},
+	)
+	filter, err := lambroll.NewLogsFilter("", "", `select(.level | ascii_downcase == "info")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	tailer = app.NewLogsTailer("/aws/lambda/hello", "short", "", &buf)
+	tailer.SetFilter(filter)
+	if _, err := tailer.Tail(ctx, 1704164646500); err != nil {
+		t.Fatal(err)
+	}
+	expected = `2024-01-02T03:04:08 {"level":"INFO","message":"world"}
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected filtered output:\n%s", buf.String())
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		since    string
+		expected time.Time
+		err      bool
+	}{
+		{since: "10m", expected: now.Add(-10 * time.Minute)},
+		{since: "2h", expected: now.Add(-2 * time.Hour)},
+		{since: "1d", expected: now.Add(-24 * time.Hour)},
+		{since: "1w", expected: now.Add(-7 * 24 * time.Hour)},
+		{since: "2024-01-01T00:00:00Z", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{since: "yesterday", err: true},
+	}
+	for _, tt := range tests {
+		got, err := lambroll.ParseSince(tt.since, now)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.since)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.since, err)
+		} else if !got.Equal(tt.expected) {
+			t.Errorf("%s: unexpected %s, expected %s", tt.since, got, tt.expected)
+		}
+	}
+}
+
+func TestLogsFilter(t *testing.T) {
+	const (
+		info     = `{"timestamp":"2024-01-02T03:04:05Z","level":"INFO","requestId":"req-1","message":"hello"}`
+		warn     = `{"timestamp":"2024-01-02T03:04:05Z","level":"WARN","requestId":"req-1","message":"slow"}`
+		errorLog = `{"timestamp":"2024-01-02T03:04:05Z","level":"ERROR","requestId":"req-2","message":"failed"}`
+		platform = `{"time":"2024-01-02T03:04:05Z","type":"platform.start","record":{"requestId":"req-1","version":"$LATEST"}}`
+		text     = "START RequestId: req-1 Version: $LATEST"
+	)
+	tests := []struct {
+		name      string
+		level     string
+		requestID string
+		query     string
+		msg       string
+		expected  string
+		ok        bool
+	}{
+		{name: "no filter", msg: text, expected: text, ok: true},
+		{name: "level matched", level: "warn", msg: warn, expected: warn, ok: true},
+		{name: "level higher", level: "warn", msg: errorLog, expected: errorLog, ok: true},
+		{name: "level lower", level: "warn", msg: info, ok: false},
+		{name: "level without level field", level: "info", msg: platform, ok: false},
+		{name: "level text", level: "info", msg: text, ok: false},
+		{name: "request id", requestID: "req-1", msg: info, expected: info, ok: true},
+		{name: "request id unmatched", requestID: "req-1", msg: errorLog, ok: false},
+		{name: "request id platform", requestID: "req-1", msg: platform, expected: platform, ok: true},
+		{name: "request id text", requestID: "req-1", msg: text, expected: text, ok: true},
+		{name: "query select", query: `select(.message == "slow")`, msg: warn, expected: `{"level":"WARN","message":"slow","requestId":"req-1","timestamp":"2024-01-02T03:04:05Z"}`, ok: true},
+		{name: "query unmatched", query: `select(.message == "slow")`, msg: info, ok: false},
+		{name: "query fields", query: `{level, message}`, msg: info, expected: `{"level":"INFO","message":"hello"}`, ok: true},
+		{name: "query false", query: `.level == "ERROR"`, msg: info, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := lambroll.NewLogsFilter(tt.level, tt.requestID, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok, err := f.Apply(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.ok {
+				t.Errorf("unexpected matched %v", ok)
+			}
+			if ok && got != tt.expected {
+				t.Errorf("unexpected output %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := lambroll.NewLogsFilter("", "", "{"); err == nil {
+		t.Error("expected error for invalid query")
+	}
+}
@@ -0,0 +1,272 @@
+package lambroll
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fujiwara/lambroll/wildcard"
+)
+
+// masker redacts secret values in outputs.
+//
+// Values resolved from secure sources (Secrets Manager, SSM SecureString) and
+// values of keys matching the key patterns are masked.
+// A masked value contains a short HMAC digest with a random key per run,
+// so that diff can report changes of masked values without revealing them.
+type masker struct {
+	mu       sync.Mutex
+	values   map[string]string // secret value => CloudFormation dynamic reference to it (if any)
+	replacer *strings.Replacer // replaces secret values in strings. nil means to be rebuilt
+	keys     []string
+	salt     []byte
+}
+
+func newMasker(keyPatterns []string) *masker {
+	salt := make([]byte, 32)
+	rand.Read(salt)
+	var keys []string
+	for _, k := range keyPatterns {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return &masker{
+		values: make(map[string]string),
+		keys:   keys,
+		salt:   salt,
+	}
+}
+
+// minSecretLength is the minimum length of secret values to be masked in larger strings.
+// Shorter values (e.g. "1", "true") are masked only in strings equal to them,
+// not to mask unrelated parts of strings.
+const minSecretLength = 6
+
+// add registers the secret value with the CloudFormation dynamic reference to it.
+// ref may be empty when the value cannot be referenced by CloudFormation.
+func (m *masker) add(v string, ref string) {
+	if m == nil || v == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[v] = ref
+	m.replacer = nil
+}
+
+// mask returns the masked representation of s.
+func (m *masker) mask(s string) string {
+	h := hmac.New(sha256.New, m.salt)
+	h.Write([]byte(s))
+	return "**masked:" + hex.EncodeToString(h.Sum(nil))[:8] + "**"
+}
+
+// isSecretKey reports whether the key matches the key patterns.
+func (m *masker) isSecretKey(key string) bool {
+	if m == nil {
+		return false
+	}
+	for _, p := range m.keys {
+		if wildcard.Match(p, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskString masks s when s is equal to any secret value,
+// and masks every occurrence of secret values in s (e.g. a password in a DSN).
+func (m *masker) maskString(s string) string {
+	if m == nil || s == "" {
+		return s
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.values[s]; ok {
+		return m.mask(s)
+	}
+	return m.secretReplacer().Replace(s)
+}
+
+// containsSecret reports whether s contains any secret value.
+func (m *masker) containsSecret(s string) bool {
+	if m == nil || s == "" {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.secretReplacer().Replace(s) != s
+}
+
+// secretReplacer returns the replacer of the secret values not shorter than minSecretLength.
+// Longer values are replaced first, so that a secret value containing another one is masked as a whole.
+// m.mu must be locked.
+func (m *masker) secretReplacer() *strings.Replacer {
+	if m.replacer != nil {
+		return m.replacer
+	}
+	var values []string
+	for v := range m.values {
+		if len(v) >= minSecretLength {
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	oldnew := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		oldnew = append(oldnew, v, m.mask(v))
+	}
+	m.replacer = strings.NewReplacer(oldnew...)
+	return m.replacer
+}
+
+// maskKeyValue masks the value of the key when the key matches the key patterns or the value is a secret value.
+func (m *masker) maskKeyValue(key, value string) string {
+	if value != "" && m.isSecretKey(key) {
+		return m.mask(value)
+	}
+	return m.maskString(value)
+}
+
+// secretReference reports whether the value of the key is a secret,
+// and returns the CloudFormation dynamic reference to the value if known.
+func (m *masker) secretReference(key, value string) (string, bool) {
+	if m == nil || value == "" {
+		return "", false
+	}
+	m.mu.Lock()
+	ref, ok := m.values[value]
+	m.mu.Unlock()
+	if ok || m.isSecretKey(key) || m.containsSecret(value) {
+		// a value containing a secret cannot be referenced by CloudFormation
+		return ref, true
+	}
+	return "", false
+}
+
+// secretVariables returns the CloudFormation dynamic references of the variables which have secret values.
+// The reference is empty when the value cannot be referenced by CloudFormation.
+func (m *masker) secretVariables(vars map[string]string) map[string]string {
+	secrets := make(map[string]string)
+	for k, v := range vars {
+		if ref, ok := m.secretReference(k, v); ok {
+			secrets[k] = ref
+		}
+	}
+	return secrets
+}
+
+// maskStringMap returns a copy of vars with masked values.
+func (m *masker) maskStringMap(vars map[string]string) map[string]string {
+	if vars == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(vars))
+	for k, v := range vars {
+		masked[k] = m.maskKeyValue(k, v)
+	}
+	return masked
+}
+
+// maskAny masks secret values in a general value (decoded from JSON)
+func (m *masker) maskAny(data any) any {
+	switch v := data.(type) {
+	case map[string]any:
+		masked := make(map[string]any, len(v))
+		for key, value := range v {
+			masked[key] = m.maskKeyAny(key, value)
+		}
+		return masked
+	case []any:
+		masked := make([]any, 0, len(v))
+		for _, value := range v {
+			masked = append(masked, m.maskAny(value))
+		}
+		return masked
+	case string:
+		return m.maskString(v)
+	default:
+		return v
+	}
+}
+
+// maskKeyAny masks the value of the key in a map
+func (m *masker) maskKeyAny(key string, value any) any {
+	if s, ok := value.(string); ok {
+		return m.maskKeyValue(key, s)
+	}
+	return m.maskAny(value)
+}
+
+// maskAnyPair masks secret values in a pair of values to compare (e.g. deployed and new).
+// When the value of a key path on the new side is a secret, the value of the same key path
+// on the old side is masked too, so that the old value of a rotated secret is not revealed.
+func (m *masker) maskAnyPair(oldData, newData any) (any, any) {
+	switch nv := newData.(type) {
+	case map[string]any:
+		ov, ok := oldData.(map[string]any)
+		if !ok {
+			break
+		}
+		maskedOld := make(map[string]any, len(ov))
+		maskedNew := make(map[string]any, len(nv))
+		for key, o := range ov {
+			if n, ok := nv[key]; ok {
+				maskedOld[key], maskedNew[key] = m.maskKeyValuePair(key, o, n)
+			} else {
+				maskedOld[key] = m.maskKeyAny(key, o)
+			}
+		}
+		for key, n := range nv {
+			if _, ok := ov[key]; !ok {
+				maskedNew[key] = m.maskKeyAny(key, n)
+			}
+		}
+		return maskedOld, maskedNew
+	case []any:
+		ov, ok := oldData.([]any)
+		if !ok {
+			break
+		}
+		maskedOld := make([]any, 0, len(ov))
+		maskedNew := make([]any, 0, len(nv))
+		for i := 0; i < len(ov) || i < len(nv); i++ {
+			switch {
+			case i < len(ov) && i < len(nv):
+				o, n := m.maskAnyPair(ov[i], nv[i])
+				maskedOld, maskedNew = append(maskedOld, o), append(maskedNew, n)
+			case i < len(ov):
+				maskedOld = append(maskedOld, m.maskAny(ov[i]))
+			default:
+				maskedNew = append(maskedNew, m.maskAny(nv[i]))
+			}
+		}
+		return maskedOld, maskedNew
+	}
+	return m.maskAny(oldData), m.maskAny(newData)
+}
+
+// maskKeyValuePair masks the old and new values of the key.
+func (m *masker) maskKeyValuePair(key string, oldValue, newValue any) (any, any) {
+	ns, ok := newValue.(string)
+	if !ok {
+		return m.maskAnyPair(oldValue, newValue)
+	}
+	if _, secret := m.secretReference(key, ns); !secret {
+		return m.maskKeyAny(key, oldValue), m.maskKeyValue(key, ns)
+	}
+	if os, ok := oldValue.(string); ok && os != "" {
+		return m.mask(os), m.mask(ns)
+	}
+	return m.maskKeyAny(key, oldValue), m.mask(ns)
+}
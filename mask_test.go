@@ -0,0 +1,131 @@
+package lambroll_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMaskerKeyPatterns(t *testing.T) {
+	m := lambroll.NewMasker([]string{"*_TOKEN", "*PASSWORD*"})
+	masked := m.MaskAny(map[string]any{
+		"Environment": map[string]any{
+			"Variables": map[string]any{
+				"API_TOKEN":      "t0ken",
+				"DB_PASSWORD_V2": "pa55",
+				"TOKEN_URL":      "https://example.com/token",
+				"EMPTY_TOKEN":    "",
+			},
+		},
+		"MemorySize": float64(128),
+	})
+	expected := map[string]any{
+		"Environment": map[string]any{
+			"Variables": map[string]any{
+				"API_TOKEN":      m.Mask("t0ken"),
+				"DB_PASSWORD_V2": m.Mask("pa55"),
+				"TOKEN_URL":      "https://example.com/token",
+				"EMPTY_TOKEN":    "",
+			},
+		},
+		"MemorySize": float64(128),
+	}
+	if diff := cmp.Diff(expected, masked); diff != "" {
+		t.Errorf("unexpected masked values (-expected +got)\n%s", diff)
+	}
+}
+
+func TestMaskerDistinguishesValues(t *testing.T) {
+	m := lambroll.NewMasker(nil)
+	a, b := m.Mask("secret-a"), m.Mask("secret-b")
+	if !strings.HasPrefix(a, "**masked:") || strings.Contains(a, "secret-a") {
+		t.Errorf("unexpected masked value: %s", a)
+	}
+	if a == b {
+		t.Errorf("different values must be masked differently: %s %s", a, b)
+	}
+	if a != m.Mask("secret-a") {
+		t.Errorf("same values must be masked equally in a run")
+	}
+	if other := lambroll.NewMasker(nil).Mask("secret-a"); other == a {
+		t.Errorf("masked values must not be comparable across runs")
+	}
+}
+
+func TestMaskerSecretValues(t *testing.T) {
+	m := lambroll.NewMasker(nil)
+	m.Add("pa55w0rd")
+	m.Add("pa55w0rd-v2")
+	m.Add("1234") // too short to be masked in larger strings
+	masked := m.MaskAny(map[string]any{
+		"Handler":     "index.handler",
+		"Description": "pa55w0rd rotated",
+		"Variables": map[string]any{
+			"PASSWORD": "pa55w0rd",
+			"DSN":      "postgres://u:pa55w0rd@h/db?fallback=pa55w0rd-v2",
+			"PIN":      "1234",
+			"BUILD":    "build 12345",
+		},
+	})
+	expected := map[string]any{
+		"Handler":     "index.handler",
+		"Description": m.Mask("pa55w0rd") + " rotated",
+		"Variables": map[string]any{
+			"PASSWORD": m.Mask("pa55w0rd"),
+			"DSN":      "postgres://u:" + m.Mask("pa55w0rd") + "@h/db?fallback=" + m.Mask("pa55w0rd-v2"),
+			"PIN":      m.Mask("1234"),
+			"BUILD":    "build 12345",
+		},
+	}
+	if diff := cmp.Diff(expected, masked); diff != "" {
+		t.Errorf("unexpected masked values (-expected +got)\n%s", diff)
+	}
+	secrets := m.SecretVariables(map[string]string{"PASSWORD": "pa55w0rd", "DSN": "postgres://u:pa55w0rd@h/db", "BUILD": "build 12345"})
+	if diff := cmp.Diff(map[string]string{"PASSWORD": "", "DSN": ""}, secrets); diff != "" {
+		t.Errorf("unexpected secret variables (-expected +got)\n%s", diff)
+	}
+}
+
+func TestMaskerPair(t *testing.T) {
+	m := lambroll.NewMasker([]string{"*_TOKEN"})
+	m.Add("n3w-pa55w0rd")
+	deployed := map[string]any{
+		"Variables": map[string]any{
+			"PASSWORD":  "old-pa55w0rd",
+			"API_TOKEN": "old-t0ken",
+			"LOG_LEVEL": "debug",
+			"REMOVED":   "removed",
+		},
+	}
+	local := map[string]any{
+		"Variables": map[string]any{
+			"PASSWORD":  "n3w-pa55w0rd",
+			"API_TOKEN": "new-t0ken",
+			"LOG_LEVEL": "info",
+		},
+	}
+	maskedDeployed, maskedLocal := m.MaskAnyPair(deployed, local)
+	expectedDeployed := map[string]any{
+		"Variables": map[string]any{
+			"PASSWORD":  m.Mask("old-pa55w0rd"),
+			"API_TOKEN": m.Mask("old-t0ken"),
+			"LOG_LEVEL": "debug",
+			"REMOVED":   "removed",
+		},
+	}
+	expectedLocal := map[string]any{
+		"Variables": map[string]any{
+			"PASSWORD":  m.Mask("n3w-pa55w0rd"),
+			"API_TOKEN": m.Mask("new-t0ken"),
+			"LOG_LEVEL": "info",
+		},
+	}
+	if diff := cmp.Diff(expectedDeployed, maskedDeployed); diff != "" {
+		t.Errorf("unexpected masked deployed values (-expected +got)\n%s", diff)
+	}
+	if diff := cmp.Diff(expectedLocal, maskedLocal); diff != "" {
+		t.Errorf("unexpected masked local values (-expected +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,278 @@
+package lambroll
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/olekukonko/tablewriter"
+)
+
+// MetricsOption represents options for Metrics()
+type MetricsOption struct {
+	Qualifier *string `help:"version or alias. if not specified, metrics of all versions of the function"`
+	Compare   string  `help:"another version or alias to compare side by side" default:""`
+	Since     string  `help:"From what time to begin the metrics" default:"1h"`
+	Until     string  `help:"To what time to end the metrics (default now)" default:""`
+	Output    string  `help:"output format (table,json)" default:"table" enum:"table,json"`
+}
+
+// lambdaMetric represents a metric of AWS/Lambda namespace and its statistic
+type lambdaMetric struct {
+	Name string
+	Stat string
+}
+
+func (m lambdaMetric) Label() string {
+	return m.Name + " " + m.Stat
+}
+
+var lambdaMetrics = []lambdaMetric{
+	{Name: "Invocations", Stat: "Sum"},
+	{Name: "Errors", Stat: "Sum"},
+	{Name: "Throttles", Stat: "Sum"},
+	{Name: "Duration", Stat: "p50"},
+	{Name: "Duration", Stat: "p99"},
+	{Name: "ConcurrentExecutions", Stat: "Maximum"},
+	{Name: "ProvisionedConcurrencyUtilization", Stat: "Maximum"},
+}
+
+// FunctionMetrics represents metrics of the function (or the version/alias) in the time range
+type FunctionMetrics struct {
+	Qualifier string              `json:"Qualifier,omitempty"`
+	Start     time.Time           `json:"Start"`
+	End       time.Time           `json:"End"`
+	Values    map[string]*float64 `json:"Values"`
+	// MaxOf is the labels of the values which are the maximum of multiple datapoints (not the statistic of the whole range)
+	MaxOf []string `json:"MaxOf,omitempty"`
+}
+
+func (m *FunctionMetrics) isMaxOf(label string) bool {
+	for _, l := range m.MaxOf {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorRate returns the rate of errors in percent
+func (m *FunctionMetrics) ErrorRate() *float64 {
+	inv, errs := m.Values[lambdaMetrics[0].Label()], m.Values[lambdaMetrics[1].Label()]
+	if inv == nil || *inv == 0 {
+		return nil
+	}
+	var e float64
+	if errs != nil {
+		e = *errs
+	}
+	return aws.Float64(e / *inv * 100)
+}
+
+// Metrics shows CloudWatch metrics of the function
+func (app *App) Metrics(ctx context.Context, opt *MetricsOption) error {
+	now := time.Now()
+	start, err := parseSince(opt.Since, now)
+	if err != nil {
+		return err
+	}
+	end := now
+	if opt.Until != "" {
+		if end, err = parseSince(opt.Until, now); err != nil {
+			return err
+		}
+	}
+	if !start.Before(end) {
+		return fmt.Errorf("--since must be before --until")
+	}
+
+	fn, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load function: %w", err)
+	}
+	qualifiers := []string{aws.ToString(opt.Qualifier)}
+	if opt.Compare != "" {
+		qualifiers = append(qualifiers, opt.Compare)
+	}
+	ms, err := app.getFunctionMetrics(ctx, *fn.FunctionName, qualifiers, start, end)
+	if err != nil {
+		return err
+	}
+	switch opt.Output {
+	case "json":
+		b, err := json.MarshalIndent(ms, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json: %w", err)
+		}
+		fmt.Println(string(b))
+	default:
+		fmt.Print(metricsTable(ms))
+	}
+	return nil
+}
+
+// getFunctionMetrics gets metrics for the qualifiers. An empty qualifier means the function (all versions).
+func (app *App) getFunctionMetrics(ctx context.Context, name string, qualifiers []string, start, end time.Time) ([]*FunctionMetrics, error) {
+	period := metricsPeriod(start, end, time.Now())
+	var queries []cwtypes.MetricDataQuery
+	ms := make([]*FunctionMetrics, 0, len(qualifiers))
+	for i, q := range qualifiers {
+		ms = append(ms, &FunctionMetrics{
+			Qualifier: q,
+			Start:     start,
+			End:       end,
+			Values:    make(map[string]*float64, len(lambdaMetrics)),
+		})
+		dimensions := []cwtypes.Dimension{
+			{Name: aws.String("FunctionName"), Value: aws.String(name)},
+		}
+		if q != "" {
+			dimensions = append(dimensions, cwtypes.Dimension{Name: aws.String("Resource"), Value: aws.String(name + ":" + q)})
+		}
+		for j, m := range lambdaMetrics {
+			queries = append(queries, cwtypes.MetricDataQuery{
+				Id:    aws.String(fmt.Sprintf("q%dm%d", i, j)),
+				Label: aws.String(m.Label()),
+				MetricStat: &cwtypes.MetricStat{
+					Metric: &cwtypes.Metric{
+						Namespace:  aws.String("AWS/Lambda"),
+						MetricName: aws.String(m.Name),
+						Dimensions: dimensions,
+					},
+					Period: aws.Int32(period),
+					Stat:   aws.String(m.Stat),
+				},
+			})
+		}
+	}
+
+	svc := cloudwatch.NewFromConfig(app.awsConfig)
+	var nextToken *string
+	for {
+		res, err := svc.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+			StartTime:         aws.Time(start),
+			EndTime:           aws.Time(end),
+			MetricDataQueries: queries,
+			NextToken:         nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metric data: %w", err)
+		}
+		for _, r := range res.MetricDataResults {
+			var i, j int
+			if _, err := fmt.Sscanf(aws.ToString(r.Id), "q%dm%d", &i, &j); err != nil || i >= len(ms) || j >= len(lambdaMetrics) {
+				log.Printf("[warn] unexpected metric data id %s", aws.ToString(r.Id))
+				continue
+			}
+			if len(r.Values) == 0 {
+				continue
+			}
+			m := lambdaMetrics[j]
+			values := r.Values
+			if cur := ms[i].Values[m.Label()]; cur != nil {
+				// merge the values from the next page
+				values = append(values, *cur)
+			}
+			if len(values) > 1 && m.Stat != "Sum" && !ms[i].isMaxOf(m.Label()) {
+				log.Printf("[warn] %s is split into multiple datapoints, showing the max of them", m.Label())
+				ms[i].MaxOf = append(ms[i].MaxOf, m.Label())
+			}
+			ms[i].Values[m.Label()] = aws.Float64(aggregateMetricValues(m.Stat, values))
+		}
+		if nextToken = res.NextToken; nextToken == nil {
+			break
+		}
+	}
+	return ms, nil
+}
+
+// metricsPeriod returns the period to aggregate the whole range into a datapoint.
+// The period must be a multiple of 300 seconds for the data older than 15 days, and 3600 seconds for older than 63 days.
+func metricsPeriod(start, end, now time.Time) int32 {
+	unit := 60.0
+	switch age := now.Sub(start); {
+	case age > 63*24*time.Hour:
+		unit = 3600
+	case age > 15*24*time.Hour:
+		unit = 300
+	}
+	return int32(math.Ceil(end.Sub(start).Seconds()/unit) * unit)
+}
+
+// aggregateMetricValues aggregates values of datapoints. Sum is summed up, and other statistics take the maximum.
+// The maximum of percentiles is not a percentile of the whole range, so the caller must label it.
+func aggregateMetricValues(stat string, values []float64) float64 {
+	var v float64
+	for i, x := range values {
+		switch {
+		case stat == "Sum":
+			v += x
+		case i == 0 || x > v:
+			v = x
+		}
+	}
+	return v
+}
+
+func metricsTable(ms []*FunctionMetrics) string {
+	buf := new(strings.Builder)
+	w := tablewriter.NewWriter(buf)
+	w.SetAutoFormatHeaders(false)
+	w.SetAutoWrapText(false)
+	header := []string{"Metric"}
+	alignment := []int{tablewriter.ALIGN_LEFT}
+	for _, m := range ms {
+		if m.Qualifier == "" {
+			header = append(header, "(all versions)")
+		} else {
+			header = append(header, m.Qualifier)
+		}
+		alignment = append(alignment, tablewriter.ALIGN_RIGHT)
+	}
+	w.SetHeader(header)
+	w.SetColumnAlignment(alignment)
+	for _, def := range lambdaMetrics {
+		row := []string{def.Label()}
+		for _, m := range ms {
+			v := formatMetricValue(def, m.Values[def.Label()])
+			if m.isMaxOf(def.Label()) {
+				v = "max of " + v
+			}
+			row = append(row, v)
+		}
+		w.Append(row)
+	}
+	row := []string{"Error rate"}
+	for _, m := range ms {
+		if r := m.ErrorRate(); r != nil {
+			row = append(row, fmt.Sprintf("%.2f%%", *r))
+		} else {
+			row = append(row, "-")
+		}
+	}
+	w.Append(row)
+	w.Render()
+	return buf.String()
+}
+
+func formatMetricValue(m lambdaMetric, v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	switch m.Name {
+	case "Duration":
+		return fmt.Sprintf("%.2f ms", *v)
+	case "ProvisionedConcurrencyUtilization":
+		return fmt.Sprintf("%.1f%%", *v*100)
+	default:
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,112 @@
+package lambroll_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetFunctionMetrics(t *testing.T) {
+	// values by metric index
+	values := map[string]string{
+		"m0": "<member>100</member><member>50</member>",   // Invocations Sum
+		"m1": "<member>3</member>",                        // Errors Sum
+		"m3": "<member>12.5</member>",                     // Duration p50
+		"m4": "<member>80.25</member><member>40</member>", // Duration p99 split into datapoints
+		"m6": "<member>0.5</member>",                      // ProvisionedConcurrencyUtilization Maximum
+	}
+	var form map[string][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if action := r.Form.Get("Action"); action != "GetMetricData" {
+			t.Errorf("unexpected action %s", action)
+		}
+		form = r.Form
+		var results strings.Builder
+		for i := 1; ; i++ {
+			id := r.Form.Get(fmt.Sprintf("MetricDataQueries.member.%d.Id", i))
+			if id == "" {
+				break
+			}
+			v := ""
+			if !strings.HasPrefix(id, "q1") { // no data for the compared qualifier
+				v = values[id[2:]]
+			}
+			fmt.Fprintf(&results, "<member><Id>%s</Id><StatusCode>Complete</StatusCode><Values>%s</Values></member>", id, v)
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<GetMetricDataResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+<GetMetricDataResult><MetricDataResults>%s</MetricDataResults></GetMetricDataResult>
+<ResponseMetadata><RequestId>req</RequestId></ResponseMetadata></GetMetricDataResponse>`, results.String())
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	app := newTestApp(t, ts)
+	end := time.Date(2024, 1, 2, 4, 0, 0, 0, time.UTC)
+	ms, err := app.GetFunctionMetrics(ctx, "hello", []string{"current", "41"}, end.Add(-90*time.Second), end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := form["MetricDataQueries.member.1.MetricStat.Period"]; len(p) != 1 || p[0] != "3600" { // older than 63 days
+		t.Errorf("unexpected period %v", p)
+	}
+	if r := form["MetricDataQueries.member.1.MetricStat.Metric.Dimensions.member.2.Value"]; len(r) != 1 || r[0] != "hello:current" {
+		t.Errorf("unexpected Resource dimension %v", r)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("unexpected metrics %d", len(ms))
+	}
+	if v := ms[0].Values["Invocations Sum"]; v == nil || *v != 150 {
+		t.Errorf("unexpected Invocations %v", v)
+	}
+	if v := ms[0].Values["Throttles Sum"]; v != nil {
+		t.Errorf("unexpected Throttles %v", *v)
+	}
+	if d := cmp.Diff([]string{"Duration p99"}, ms[0].MaxOf); d != "" {
+		t.Errorf("unexpected MaxOf: %s", d)
+	}
+	if r := ms[0].ErrorRate(); r == nil || *r != 2 {
+		t.Errorf("unexpected error rate %v", r)
+	}
+
+	table := lambroll.MetricsTable(ms)
+	for _, s := range []string{
+		"Metric", "current", "41",
+		"| Invocations Sum", " 150 |",
+		"| Duration p50", " 12.50 ms |",
+		"| Duration p99", " max of 80.25 ms |",
+		"| ProvisionedConcurrencyUtilization Maximum", " 50.0% |",
+		"| Error rate", " 2.00% |",
+	} {
+		if !strings.Contains(table, s) {
+			t.Errorf("table does not contain %q\n%s", s, table)
+		}
+	}
+}
+
+func TestMetricsPeriod(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	for _, c := range []struct {
+		start, end time.Time
+		period     int32
+	}{
+		{now.Add(-90 * time.Second), now, 120},
+		{now.Add(-1 * day), now, 86400},
+		{now.Add(-20 * day), now.Add(-20*day + 10*time.Minute + time.Second), 900},
+		{now.Add(-70 * day), now.Add(-70*day + 10*time.Minute), 3600},
+		{now.Add(-70 * day), now.Add(-69 * day), 86400},
+	} {
+		if p := lambroll.MetricsPeriod(c.start, c.end, now); p != c.period {
+			t.Errorf("unexpected period %d for %s - %s, expected %d", p, c.start, c.end, c.period)
+		}
+	}
+}
@@ -19,3 +19,12 @@ func (opt *ZipOption) Expand() error {
 	opt.excludes = append(opt.excludes, excludes...)
 	return nil
 }
+
+// expandZipOption expands the exclude file and excludes the overlay files for the env
+func (app *App) expandZipOption(opt *ZipOption) error {
+	if err := opt.Expand(); err != nil {
+		return err
+	}
+	opt.excludes = append(opt.excludes, app.overlayExcludes()...)
+	return nil
+}
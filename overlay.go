@@ -0,0 +1,124 @@
+package lambroll
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var overlayExtensions = []string{".json", ".jsonnet", ".yaml", ".yml"}
+
+// overlayFilenames returns candidates of the overlay file for env.
+// e.g. function.json + prod => function.prod.json, function.prod.jsonnet, ...
+func overlayFilenames(path string, env string) []string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	names := []string{base + "." + env + ext}
+	for _, e := range overlayExtensions {
+		if e != ext {
+			names = append(names, base+"."+env+e)
+		}
+	}
+	return names
+}
+
+// overlayExcludes returns the overlay files for app.env to exclude from the archive.
+// The files for other envs are not excluded, because they may be a part of the function code (e.g. function.schema.json).
+func (app *App) overlayExcludes() []string {
+	if app.env == "" {
+		return nil
+	}
+	paths := []string{DefaultFunctionFilenames[0], DefaultFunctionURLFilenames[0]}
+	if app.functionFilePath != "" {
+		paths = append(paths, filepath.Base(app.functionFilePath))
+	}
+	var excludes []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		for _, name := range overlayFilenames(path, app.env) {
+			if !seen[name] {
+				seen[name] = true
+				excludes = append(excludes, name)
+			}
+		}
+	}
+	return excludes
+}
+
+func findOverlayFile(path string, env string) (string, error) {
+	names := overlayFilenames(path, env)
+	for _, name := range names {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("overlay file for env %s (%s): %w", env, strings.Join(names, " or "), os.ErrNotExist)
+}
+
+func (app *App) mergeOverlay(src []byte, path string, required bool) ([]byte, error) {
+	overlayPath, err := findOverlayFile(path, app.env)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			log.Printf("[debug] %s. skip merging", err)
+			return src, nil
+		}
+		return nil, err
+	}
+	log.Printf("[info] merging overlay %s into %s", overlayPath, path)
+	overlay, err := app.readDefinitionFile(overlayPath)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeDefinition(src, overlay, app.overlayArrayMerge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge %s into %s: %w", overlayPath, path, err)
+	}
+	return merged, nil
+}
+
+// mergeDefinition deep-merges the overlay JSON into the base JSON.
+// Objects are merged recursively, null in the overlay removes the key,
+// and arrays are replaced or appended by arrayMerge.
+func mergeDefinition(base, overlay []byte, arrayMerge string) ([]byte, error) {
+	var b, o any
+	if err := json.Unmarshal(base, &b); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(overlay, &o); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValues(b, o, arrayMerge == "append"))
+}
+
+func mergeValues(base, overlay any, appendArrays bool) any {
+	switch o := overlay.(type) {
+	case map[string]any:
+		b, ok := base.(map[string]any)
+		if !ok {
+			return o
+		}
+		merged := make(map[string]any, len(b))
+		for k, v := range b {
+			merged[k] = v
+		}
+		for k, v := range o {
+			if v == nil {
+				delete(merged, k)
+				continue
+			}
+			merged[k] = mergeValues(merged[k], v, appendArrays)
+		}
+		return merged
+	case []any:
+		if b, ok := base.([]any); ok && appendArrays {
+			return append(append([]any{}, b...), o...)
+		}
+		return o
+	default:
+		return o
+	}
+}
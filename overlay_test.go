@@ -0,0 +1,82 @@
+package lambroll_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testOverlayBase = `{
+  "FunctionName": "hello",
+  "MemorySize": 128,
+  "Environment": {"Variables": {"FOO": "foo", "BAR": "bar"}},
+  "Layers": ["layer-a"],
+  "VpcConfig": {"SubnetIds": ["subnet-a"]}
+}`
+
+var testOverlay = `{
+  "FunctionName": "hello-prod",
+  "MemorySize": 1024,
+  "Environment": {"Variables": {"FOO": "FOO", "BAZ": "baz"}},
+  "Layers": ["layer-b"],
+  "VpcConfig": null
+}`
+
+var testCasesMergeDefinition = []struct {
+	arrayMerge string
+	expected   string
+}{
+	{
+		arrayMerge: "replace",
+		expected:   `{"Environment":{"Variables":{"BAR":"bar","BAZ":"baz","FOO":"FOO"}},"FunctionName":"hello-prod","Layers":["layer-b"],"MemorySize":1024}`,
+	},
+	{
+		arrayMerge: "append",
+		expected:   `{"Environment":{"Variables":{"BAR":"bar","BAZ":"baz","FOO":"FOO"}},"FunctionName":"hello-prod","Layers":["layer-a","layer-b"],"MemorySize":1024}`,
+	},
+}
+
+func TestMergeDefinition(t *testing.T) {
+	for _, c := range testCasesMergeDefinition {
+		t.Run(c.arrayMerge, func(t *testing.T) {
+			b, err := lambroll.MergeDefinition([]byte(testOverlayBase), []byte(testOverlay), c.arrayMerge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(c.expected, string(b)); diff != "" {
+				t.Errorf("(-expected +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOverlayFilenames(t *testing.T) {
+	expected := []string{
+		"dir/function.prod.jsonnet",
+		"dir/function.prod.json",
+		"dir/function.prod.yaml",
+		"dir/function.prod.yml",
+	}
+	if diff := cmp.Diff(expected, lambroll.OverlayFilenames("dir/function.jsonnet", "prod")); diff != "" {
+		t.Errorf("(-expected +got)\n%s", diff)
+	}
+}
+
+func TestOverlayExcludes(t *testing.T) {
+	app := newTestAppWithOption(t, nil, &lambroll.Option{Function: "test/function.json", Env: "prod"})
+	expected := []string{
+		"function.prod.json", "function.prod.jsonnet", "function.prod.yaml", "function.prod.yml",
+		"function_url.prod.json", "function_url.prod.jsonnet", "function_url.prod.yaml", "function_url.prod.yml",
+	}
+	excludes := app.OverlayExcludes()
+	if diff := cmp.Diff(expected, excludes); diff != "" {
+		t.Errorf("(-expected +got)\n%s", diff)
+	}
+	for _, name := range []string{"function.schema.json", "function.staging.json"} {
+		if slices.Contains(excludes, name) {
+			t.Errorf("%s must not be excluded", name)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 type RenderOption struct {
 	Jsonnet     bool   `default:"false" help:"render function.json as jsonnet"`
+	Format      string `default:"json" enum:"json,jsonnet,yaml" help:"output format (json,jsonnet,yaml)"`
 	FunctionURL string `help:"render function-url definition file" default:"" env:"LAMBROLL_FUNCTION_URL"`
 }
 
@@ -23,22 +24,24 @@ func (app *App) Render(ctx context.Context, opt *RenderOption) error {
 		if err != nil {
 			return fmt.Errorf("failed to load function-url: %w", err)
 		}
-		b, err = marshalJSON(fu)
+		b, err = app.marshalJSONMasked(fu)
 		if err != nil {
 			return fmt.Errorf("failed to marshal function-url: %w", err)
 		}
 	} else {
-		b, err = marshalJSON(fn)
+		b, err = app.marshalJSONMasked(fn)
 		if err != nil {
 			return fmt.Errorf("failed to marshal function: %w", err)
 		}
 	}
 
+	format := opt.Format
 	if opt.Jsonnet {
-		b, err = jsonToJsonnet(b, app.functionFilePath)
-		if err != nil {
-			return fmt.Errorf("failed to render function.json as jsonnet: %w", err)
-		}
+		format = "jsonnet"
+	}
+	b, err = convertDefinition(b, format, app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to render function.json as %s: %w", format, err)
 	}
 	if _, err := os.Stdout.Write(b); err != nil {
 		return fmt.Errorf("failed to write function.json: %w", err)
@@ -2,10 +2,10 @@ package lambroll
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
-	"strconv"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
@@ -18,6 +18,11 @@ type RollbackOption struct {
 	Alias         string `default:"current" help:"alias to rollback"`
 	Version       string `default:"" help:"version to rollback (default: previous version auto detected)"`
 	DeleteVersion bool   `default:"false" help:"delete rolled back version"`
+	Strategy      string `default:"version" enum:"version,publish-time,alias-history" help:"strategy to detect the previous version (version,publish-time,alias-history)"`
+	ToCommit      string `default:"" help:"rollback to the latest version whose description contains the string (e.g. commit hash)"`
+	Restore       bool   `default:"false" help:"restore tags, function url and permissions from the snapshot of the version saved by deploy --snapshot"`
+	Snapshot      string `default:"" help:"location of snapshots (local directory or s3://bucket/prefix)" env:"LAMBROLL_SNAPSHOT"`
+	AliasHistory  bool   `default:"false" help:"record the previous version in the alias description for rollback --strategy=alias-history" env:"LAMBROLL_ALIAS_HISTORY"`
 }
 
 func (opt RollbackOption) label() string {
@@ -34,6 +39,13 @@ func (app *App) Rollback(ctx context.Context, opt *RollbackOption) error {
 		return fmt.Errorf("failed to load function: %w", err)
 	}
 
+	if opt.Version != "" && opt.ToCommit != "" {
+		return fmt.Errorf("--version and --to-commit cannot be specified at the same time")
+	}
+	if opt.Restore && opt.Snapshot == "" {
+		return fmt.Errorf("--restore requires --snapshot")
+	}
+
 	log.Printf("[info] starting rollback function %s:%s", *fn.FunctionName, opt.Alias)
 
 	res, err := app.lambda.GetAlias(ctx, &lambda.GetAliasInput{
@@ -49,68 +61,140 @@ func (app *App) Rollback(ctx context.Context, opt *RollbackOption) error {
 	if opt.Version != "" {
 		prevVersion = opt.Version
 	} else {
-		prevVersion, err = app.findPreviousVersion(ctx, *fn.FunctionName, currentVersion)
+		prevVersion, err = app.findPreviousVersion(ctx, *fn.FunctionName, currentVersion, aws.ToString(res.Description), opt)
 		if err != nil {
 			return fmt.Errorf("failed to find previous version: %w", err)
 		}
 	}
 
+	var snap *functionSnapshot
+	if opt.Restore {
+		// load the snapshot before rolling back to fail early
+		if snap, err = app.loadSnapshot(ctx, opt.Snapshot, *fn.FunctionName, prevVersion); err != nil {
+			return fmt.Errorf("failed to load snapshot: %w", err)
+		}
+	}
+
 	log.Printf("[info] rolling back function version %s to %s %s", currentVersion, prevVersion, opt.label())
-	if opt.DryRun {
-		return nil
+	if !opt.DryRun {
+		err = app.updateAliases(ctx, *fn.FunctionName, versionAlias{
+			Version:        prevVersion,
+			Name:           opt.Alias,
+			RecordPrevious: opt.AliasHistory,
+		})
+		if err != nil {
+			return err
+		}
 	}
-	err = app.updateAliases(ctx, *fn.FunctionName, versionAlias{Version: prevVersion, Name: opt.Alias})
-	if err != nil {
-		return err
+
+	// restore after the alias is rolled back, not to change the state of the current version on failure
+	if snap != nil {
+		if err := app.restoreSnapshot(ctx, snap, opt.Alias, opt.DryRun); err != nil {
+			return fmt.Errorf("failed to restore snapshot (alias %s is already rolled back to version %s): %w", opt.Alias, prevVersion, err)
+		}
 	}
 
-	if !opt.DeleteVersion {
+	if opt.DryRun || !opt.DeleteVersion {
 		return nil
 	}
 
 	return app.deleteFunctionVersion(ctx, *fn.FunctionName, currentVersion)
 }
 
-func (app *App) findPreviousVersion(ctx context.Context, name, currentVersion string) (string, error) {
+func (app *App) findPreviousVersion(ctx context.Context, name, currentVersion, aliasDescription string, opt *RollbackOption) (string, error) {
 	aliases, err := app.getAliases(ctx, name)
 	if err != nil {
 		return "", fmt.Errorf("failed to get aliases: %w", err)
 	}
-	cv, err := strconv.ParseInt(currentVersion, 10, 64)
+	versions, err := app.listVersions(ctx, name)
 	if err != nil {
-		return "", fmt.Errorf("failed to pase %s as int: %w", currentVersion, err)
+		return "", err
 	}
+	return selectPreviousVersion(versions, aliases, currentVersion, aliasDescription, opt)
+}
 
-	var prevVersion string
-VERSIONS:
-	for v := cv - 1; v > 0; v-- {
-		log.Printf("[debug] get function version %d", v)
-		vs := strconv.FormatInt(v, 10)
-		res, err := app.lambda.GetFunction(ctx, &lambda.GetFunctionInput{
-			FunctionName: aws.String(name),
-			Qualifier:    aws.String(vs),
-		})
-		if err != nil {
-			var nfe *types.ResourceNotFoundException
-			if errors.As(err, &nfe) {
-				log.Printf("[debug] version %s not found", vs)
-				continue VERSIONS
-			} else {
-				return "", fmt.Errorf("failed to get function: %w", err)
+// selectPreviousVersion selects the version to rollback from the versions by the strategy of opt
+func selectPreviousVersion(versions []types.FunctionConfiguration, aliases map[string][]string, currentVersion, aliasDescription string, opt *RollbackOption) (string, error) {
+	published, err := newPublishedVersions(versions)
+	if err != nil {
+		return "", err
+	}
+	var current *publishedVersion
+	for _, v := range published {
+		if v.Version == currentVersion {
+			current = &v
+			break
+		}
+	}
+
+	if opt.ToCommit != "" {
+		for _, v := range published {
+			if v.Version != currentVersion && strings.Contains(v.Description, opt.ToCommit) {
+				log.Printf("[info] version %s matches %q by description: %s", v.Version, opt.ToCommit, v.Description)
+				return v.Version, nil
 			}
 		}
-		if pv := *res.Configuration.Version; aliases[pv] != nil {
+		return "", fmt.Errorf("no version found whose description contains %q", opt.ToCommit)
+	}
+
+	if opt.Strategy == "alias-history" {
+		prev := previousVersionFromAliasDescription(aliasDescription)
+		if prev == "" {
+			return "", fmt.Errorf("the previous version is not recorded in the alias description. the alias must be updated by lambroll deploy or rollback")
+		}
+		for _, v := range published {
+			if v.Version == prev {
+				log.Printf("[info] the alias pointed at version %s before", prev)
+				return prev, nil
+			}
+		}
+		return "", fmt.Errorf("version %s that the alias pointed at is not found", prev)
+	}
+
+	if current == nil {
+		return "", fmt.Errorf("current version %s is not a published version", currentVersion)
+	}
+	if opt.Strategy == "publish-time" {
+		sort.SliceStable(published, func(i, j int) bool { return published[i].LastModified.After(published[j].LastModified) })
+	}
+	for _, v := range published {
+		switch opt.Strategy {
+		case "publish-time":
+			if !v.LastModified.Before(current.LastModified) {
+				continue
+			}
+		default:
+			if v.Number >= current.Number {
+				continue
+			}
+		}
+		if as := aliases[v.Version]; as != nil {
 			// skip if the version has alias
-			log.Printf("[info] version %s has alias %v, skipping", pv, aliases[pv])
-			continue VERSIONS
+			log.Printf("[info] version %s has alias %v, skipping", v.Version, as)
+			continue
 		}
-		prevVersion = *res.Configuration.Version
-		break
+		return v.Version, nil
+	}
+	return "", fmt.Errorf("unable to detect previous version of function")
+}
+
+// aliasPreviousVersionPrefix is the prefix of alias descriptions which record the previous version
+const aliasPreviousVersionPrefix = "lambroll:previous-version="
+
+// aliasDescriptionWithPrevious returns the alias description which records the previous version.
+// It returns nil to keep the description as is when the description is not managed by lambroll.
+func aliasDescriptionWithPrevious(desc, prevVersion string) *string {
+	if desc != "" && !strings.HasPrefix(desc, aliasPreviousVersionPrefix) {
+		return nil
 	}
-	if prevVersion == "" {
-		return "", fmt.Errorf("unable to detect previous version of function")
+	return aws.String(aliasPreviousVersionPrefix + prevVersion)
+}
+
+func previousVersionFromAliasDescription(desc string) string {
+	if !strings.HasPrefix(desc, aliasPreviousVersionPrefix) {
+		return ""
 	}
-	return prevVersion, nil
+	return strings.TrimPrefix(desc, aliasPreviousVersionPrefix)
 }
 
 func (app *App) deleteFunctionVersion(ctx context.Context, functionName, version string) error {
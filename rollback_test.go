@@ -0,0 +1,130 @@
+package lambroll_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/fujiwara/lambroll"
+)
+
+var testRollbackVersions = []types.FunctionConfiguration{
+	{Version: aws.String("$LATEST"), LastModified: aws.String("2024-01-07T00:00:00.000+0000")},
+	{Version: aws.String("1"), LastModified: aws.String("2024-01-01T00:00:00.000+0000"), Description: aws.String("commit 0000000")},
+	{Version: aws.String("2"), LastModified: aws.String("2024-01-02T00:00:00.000+0000"), Description: aws.String("commit abc1234")},
+	{Version: aws.String("3"), LastModified: aws.String("2024-01-03T00:00:00.000+0000"), Description: aws.String("commit abc1234")},
+	// version 4 was deleted
+	{Version: aws.String("5"), LastModified: aws.String("2024-01-05T00:00:00.000+0000"), Description: aws.String("commit def5678")},
+	{Version: aws.String("6"), LastModified: aws.String("2024-01-06T00:00:00.000+0000"), Description: aws.String("commit 9876543")},
+	// version 7 has an older LastModified than 6 to distinguish publish-time from version
+	{Version: aws.String("7"), LastModified: aws.String("2024-01-04T00:00:00.000+0000"), Description: aws.String("commit fedcba9")},
+}
+
+var testRollbackAliases = map[string][]string{
+	"5": {"canary"},
+	"6": {"current"},
+}
+
+var testSelectPreviousVersionCases = []struct {
+	current     string
+	description string
+	opt         lambroll.RollbackOption
+	expected    string
+	err         bool
+}{
+	{current: "6", opt: lambroll.RollbackOption{Strategy: "version"}, expected: "3"},
+	{current: "3", opt: lambroll.RollbackOption{Strategy: "version"}, expected: "2"},
+	{current: "1", opt: lambroll.RollbackOption{Strategy: "version"}, err: true},
+	{current: "6", opt: lambroll.RollbackOption{Strategy: "publish-time"}, expected: "7"},
+	{current: "7", opt: lambroll.RollbackOption{Strategy: "publish-time"}, expected: "3"},
+	{current: "$LATEST", opt: lambroll.RollbackOption{Strategy: "version"}, err: true},
+	{current: "6", description: "lambroll:previous-version=5", opt: lambroll.RollbackOption{Strategy: "alias-history"}, expected: "5"},
+	{current: "6", description: "lambroll:previous-version=4", opt: lambroll.RollbackOption{Strategy: "alias-history"}, err: true},
+	{current: "6", description: "production", opt: lambroll.RollbackOption{Strategy: "alias-history"}, err: true},
+	{current: "6", opt: lambroll.RollbackOption{Strategy: "version", ToCommit: "abc1234"}, expected: "3"},
+	{current: "6", opt: lambroll.RollbackOption{Strategy: "version", ToCommit: "def5678"}, expected: "5"},
+	{current: "6", opt: lambroll.RollbackOption{Strategy: "version", ToCommit: "9876543"}, err: true},
+}
+
+func TestSelectPreviousVersion(t *testing.T) {
+	for _, c := range testSelectPreviousVersionCases {
+		name := fmt.Sprintf("%s/%s/%s", c.current, c.opt.Strategy, c.opt.ToCommit)
+		t.Run(name, func(t *testing.T) {
+			v, err := lambroll.SelectPreviousVersion(testRollbackVersions, testRollbackAliases, c.current, c.description, &c.opt)
+			if c.err {
+				if err == nil {
+					t.Errorf("expected error but got version %s", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != c.expected {
+				t.Errorf("expected version %s but got %s", c.expected, v)
+			}
+		})
+	}
+}
+
+func TestAliasDescription(t *testing.T) {
+	if d := lambroll.AliasDescription("", "3"); aws.ToString(d) != "lambroll:previous-version=3" {
+		t.Errorf("unexpected description %v", d)
+	}
+	if d := lambroll.AliasDescription("lambroll:previous-version=2", "3"); aws.ToString(d) != "lambroll:previous-version=3" {
+		t.Errorf("unexpected description %v", d)
+	}
+	if d := lambroll.AliasDescription("production", "3"); d != nil {
+		t.Errorf("description not managed by lambroll must be kept %s", *d)
+	}
+}
+
+func TestUpdateAliasRecordPrevious(t *testing.T) {
+	var getAliasCalls int
+	var updated string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/2015-03-31/functions/hello/aliases/current":
+			getAliasCalls++
+			fmt.Fprint(w, `{"Name":"current","FunctionVersion":"5","Description":""}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/2015-03-31/functions/hello/aliases/broken":
+			w.Header().Set("X-Amzn-Errortype", "AccessDeniedException")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"Type":"User","message":"not authorized"}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/2015-03-31/functions/hello/aliases/current":
+			b, _ := io.ReadAll(r.Body)
+			updated = string(b)
+			fmt.Fprint(w, `{"Name":"current","FunctionVersion":"6"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+	app := newTestApp(t, ts)
+
+	if err := app.UpdateAlias(ctx, "hello", "current", "6", false); err != nil {
+		t.Fatal(err)
+	}
+	if getAliasCalls != 0 || updated != `{"FunctionVersion":"6"}` {
+		t.Errorf("the alias description must be kept without recording: %d %s", getAliasCalls, updated)
+	}
+
+	if err := app.UpdateAlias(ctx, "hello", "current", "6", true); err != nil {
+		t.Fatal(err)
+	}
+	if updated != `{"Description":"lambroll:previous-version=5","FunctionVersion":"6"}` {
+		t.Errorf("the previous version must be recorded: %s", updated)
+	}
+
+	if err := app.UpdateAlias(ctx, "hello", "broken", "6", true); err == nil {
+		t.Error("failure of GetAlias must be an error")
+	}
+}
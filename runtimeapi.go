@@ -0,0 +1,204 @@
+package lambroll
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const runtimeAPIPrefix = "/2018-06-01/runtime/"
+
+// runtimeInvocation represents an invocation passed to the runtime via the Runtime API
+type runtimeInvocation struct {
+	RequestID   string
+	Payload     []byte
+	Deadline    time.Time
+	FunctionArn string
+
+	done chan *runtimeResponse
+}
+
+// runtimeResponse represents a response or an error posted by the runtime
+type runtimeResponse struct {
+	Payload       []byte
+	FunctionError string // "Unhandled" when the runtime posted an error
+	ErrorType     string
+}
+
+// runtimeAPI is an in-process implementation of the Lambda Runtime API
+// https://docs.aws.amazon.com/lambda/latest/dg/runtimes-api.html
+type runtimeAPI struct {
+	next    chan *runtimeInvocation
+	initErr chan *runtimeResponse
+
+	mu          sync.Mutex
+	invocations map[string]*runtimeInvocation
+
+	listener net.Listener
+	server   *http.Server
+}
+
+func newRuntimeAPI() (*runtimeAPI, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the runtime api: %w", err)
+	}
+	api := &runtimeAPI{
+		next:        make(chan *runtimeInvocation, 1),
+		initErr:     make(chan *runtimeResponse, 1),
+		invocations: make(map[string]*runtimeInvocation),
+		listener:    l,
+	}
+	api.server = &http.Server{Handler: api}
+	go api.server.Serve(l)
+	log.Printf("[debug] runtime api listening on %s", api.Addr())
+	return api, nil
+}
+
+// Addr returns the address for AWS_LAMBDA_RUNTIME_API
+func (api *runtimeAPI) Addr() string {
+	return api.listener.Addr().String()
+}
+
+func (api *runtimeAPI) Close() error {
+	return api.server.Close()
+}
+
+// Enqueue passes the invocation to the runtime
+func (api *runtimeAPI) Enqueue(inv *runtimeInvocation) {
+	inv.done = make(chan *runtimeResponse, 1)
+	api.mu.Lock()
+	api.invocations[inv.RequestID] = inv
+	api.mu.Unlock()
+	api.next <- inv
+}
+
+// Forget discards the invocation which will not be waited any more (e.g. timed out).
+// It reports whether the runtime had not received the invocation yet.
+func (api *runtimeAPI) Forget(inv *runtimeInvocation) bool {
+	api.mu.Lock()
+	delete(api.invocations, inv.RequestID)
+	api.mu.Unlock()
+	// drain the invocation if the runtime has not received it yet
+	select {
+	case queued := <-api.next:
+		if queued != inv {
+			api.next <- queued
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// reportInitError passes the init error to the invocation waiting for it.
+// An init error which is not received yet is kept, and later ones are discarded not to block the runtime.
+func (api *runtimeAPI) reportInitError(res *runtimeResponse) {
+	select {
+	case api.initErr <- res:
+	default:
+		log.Printf("[warn] runtime api: discarded init error %s", res.ErrorType)
+	}
+}
+
+// ResetInitError discards the init error reported by the previous runtime
+func (api *runtimeAPI) ResetInitError() {
+	select {
+	case <-api.initErr:
+	default:
+	}
+}
+
+func (api *runtimeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, runtimeAPIPrefix)
+	log.Printf("[trace] runtime api %s %s", r.Method, r.URL.Path)
+	switch {
+	case r.Method == http.MethodGet && path == "invocation/next":
+		api.handleNext(w, r)
+	case r.Method == http.MethodPost && path == "init/error":
+		api.reportInitError(readRuntimeResponse(r, "Unhandled"))
+		writeRuntimeStatus(w, http.StatusAccepted)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "invocation/"):
+		id, kind, _ := strings.Cut(strings.TrimPrefix(path, "invocation/"), "/")
+		var functionError string
+		switch kind {
+		case "response":
+		case "error":
+			functionError = "Unhandled"
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		api.mu.Lock()
+		inv, ok := api.invocations[id]
+		delete(api.invocations, id)
+		api.mu.Unlock()
+		if !ok {
+			log.Printf("[warn] runtime api: unknown request id %s", id)
+			http.Error(w, `{"errorMessage":"unknown request id","errorType":"InvalidRequestID"}`, http.StatusBadRequest)
+			return
+		}
+		inv.done <- readRuntimeResponse(r, functionError)
+		writeRuntimeStatus(w, http.StatusAccepted)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (api *runtimeAPI) handleNext(w http.ResponseWriter, r *http.Request) {
+	var inv *runtimeInvocation
+	select {
+	case inv = <-api.next:
+	case <-r.Context().Done():
+		return
+	}
+	if r.Context().Err() != nil {
+		// the runtime has gone. pass the invocation to the next runtime
+		api.next <- inv
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("Lambda-Runtime-Aws-Request-Id", inv.RequestID)
+	h.Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(inv.Deadline.UnixMilli(), 10))
+	h.Set("Lambda-Runtime-Invoked-Function-Arn", inv.FunctionArn)
+	h.Set("Lambda-Runtime-Trace-Id", "Root="+newRequestID())
+	w.WriteHeader(http.StatusOK)
+	w.Write(inv.Payload)
+}
+
+func readRuntimeResponse(r *http.Request, functionError string) *runtimeResponse {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("[warn] runtime api: failed to read body: %s", err)
+	}
+	return &runtimeResponse{
+		Payload:       b,
+		FunctionError: functionError,
+		ErrorType:     r.Header.Get("Lambda-Runtime-Function-Error-Type"),
+	}
+}
+
+func writeRuntimeStatus(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"status": "OK"})
+}
+
+// newRequestID generates a random UUID (version 4)
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
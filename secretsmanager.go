@@ -0,0 +1,123 @@
+package lambroll
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// SecretsManager resolves secret values from AWS Secrets Manager.
+// Resolved values are cached per run and registered to the masker.
+type SecretsManager struct {
+	cache    sync.Map
+	masker   *masker
+	Resolver func(ctx context.Context, secretID string) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+func newSecretsManager(cfg aws.Config, m *masker) *SecretsManager {
+	return &SecretsManager{
+		masker: m,
+		Resolver: func(ctx context.Context, secretID string) (*secretsmanager.GetSecretValueOutput, error) {
+			return secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+				SecretId: aws.String(secretID),
+			})
+		},
+	}
+}
+
+func (s *SecretsManager) secretString(ctx context.Context, secretID string) (string, error) {
+	if v, ok := s.cache.Load(secretID); ok {
+		return v.(string), nil
+	}
+	res, err := s.Resolver(ctx, secretID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret value %s: %w", secretID, err)
+	}
+	var v string
+	if res.SecretString != nil {
+		v = *res.SecretString
+	} else {
+		v = string(res.SecretBinary)
+	}
+	s.cache.Store(secretID, v)
+	return v, nil
+}
+
+// Lookup returns the secret value. If jsonKey is given, the secret value is
+// parsed as a JSON object and the value of the key is returned.
+// The returned value is registered to the masker.
+func (s *SecretsManager) Lookup(ctx context.Context, secretID string, jsonKey ...string) (string, error) {
+	if len(jsonKey) > 1 {
+		return "", fmt.Errorf("secretsmanager accepts at most 2 parameters, but got %d", len(jsonKey)+1)
+	}
+	v, err := s.secretString(ctx, secretID)
+	if err != nil {
+		return "", err
+	}
+	ref := fmt.Sprintf("{{resolve:secretsmanager:%s}}", secretID)
+	if len(jsonKey) == 1 {
+		ref = fmt.Sprintf("{{resolve:secretsmanager:%s:SecretString:%s}}", secretID, jsonKey[0])
+		var m map[string]any
+		if err := json.Unmarshal([]byte(v), &m); err != nil {
+			return "", fmt.Errorf("secret %s is not a JSON object: %w", secretID, err)
+		}
+		mv, ok := m[jsonKey[0]]
+		if !ok {
+			return "", fmt.Errorf("key %s is not found in secret %s", jsonKey[0], secretID)
+		}
+		if sv, ok := mv.(string); ok {
+			v = sv
+		} else {
+			b, _ := json.Marshal(mv)
+			v = string(b)
+		}
+	}
+	s.masker.add(v, ref)
+	return v, nil
+}
+
+func (s *SecretsManager) JsonnetNativeFuncs(ctx context.Context) []*jsonnet.NativeFunction {
+	return []*jsonnet.NativeFunction{
+		{
+			Name:   "secretsmanager",
+			Params: []ast.Identifier{"secret_id"},
+			Func: func(params []any) (any, error) {
+				id, ok := params[0].(string)
+				if !ok {
+					return nil, fmt.Errorf("secretsmanager: secret_id must be a string")
+				}
+				return s.Lookup(ctx, id)
+			},
+		},
+		{
+			Name:   "secretsmanager_json",
+			Params: []ast.Identifier{"secret_id", "json_key"},
+			Func: func(params []any) (any, error) {
+				id, ok := params[0].(string)
+				if !ok {
+					return nil, fmt.Errorf("secretsmanager_json: secret_id must be a string")
+				}
+				key, ok := params[1].(string)
+				if !ok {
+					return nil, fmt.Errorf("secretsmanager_json: json_key must be a string")
+				}
+				return s.Lookup(ctx, id, key)
+			},
+		},
+	}
+}
+
+func (s *SecretsManager) FuncMap(ctx context.Context) template.FuncMap {
+	return template.FuncMap{
+		"secretsmanager": func(secretID string, jsonKey ...string) (string, error) {
+			return s.Lookup(ctx, secretID, jsonKey...)
+		},
+	}
+}
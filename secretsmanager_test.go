@@ -0,0 +1,71 @@
+package lambroll_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSecretsManager(t *testing.T) {
+	m := lambroll.NewMasker(nil)
+	s := lambroll.NewSecretsManager(aws.Config{}, m)
+	calls := 0
+	s.Resolver = func(_ context.Context, id string) (*secretsmanager.GetSecretValueOutput, error) {
+		calls++
+		switch id {
+		case "plain":
+			return &secretsmanager.GetSecretValueOutput{SecretString: aws.String("s3cr3t")}, nil
+		case "json":
+			return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"username":"lambroll","password":"pa55w0rd","port":3306}`)}, nil
+		}
+		t.Fatalf("unexpected secret id: %s", id)
+		return nil, nil
+	}
+	ctx := context.Background()
+	if v, err := s.Lookup(ctx, "plain"); err != nil || v != "s3cr3t" {
+		t.Errorf("unexpected plain secret: %s %v", v, err)
+	}
+	if v, err := s.Lookup(ctx, "json", "password"); err != nil || v != "pa55w0rd" {
+		t.Errorf("unexpected json secret: %s %v", v, err)
+	}
+	if v, err := s.Lookup(ctx, "json", "username"); err != nil || v != "lambroll" {
+		t.Errorf("unexpected json secret: %s %v", v, err)
+	}
+	if v, err := s.Lookup(ctx, "json", "port"); err != nil || v != "3306" {
+		t.Errorf("unexpected json secret: %s %v", v, err)
+	}
+	if _, err := s.Lookup(ctx, "json", "missing"); err == nil {
+		t.Error("expected error for missing key")
+	}
+	if calls != 2 {
+		t.Errorf("secret values must be cached. resolver called %d times", calls)
+	}
+
+	masked := m.MaskAny(map[string]any{
+		"Variables": map[string]any{
+			"TOKEN":    "s3cr3t",
+			"PASSWORD": "pa55w0rd",
+			"DB_USER":  m.Mask("lambroll"),
+			"DSN":      "mysql://admin:" + m.Mask("pa55w0rd") + "@localhost/db",
+			"DB_PORT":  "3306",
+			"LOG_MODE": "debug",
+		},
+	})
+	expected := map[string]any{
+		"Variables": map[string]any{
+			"TOKEN":    m.Mask("s3cr3t"),
+			"PASSWORD": m.Mask("pa55w0rd"),
+			"DB_USER":  m.Mask("lambroll"),
+			"DSN":      "mysql://admin:" + m.Mask("pa55w0rd") + "@localhost/db",
+			"DB_PORT":  m.Mask("3306"),
+			"LOG_MODE": "debug",
+		},
+	}
+	if diff := cmp.Diff(expected, masked); diff != "" {
+		t.Errorf("unexpected masked values (-expected +got)\n%s", diff)
+	}
+}
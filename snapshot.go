@@ -0,0 +1,429 @@
+package lambroll
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aereal/jsondiff"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// functionSnapshot represents the state of a function which is not versioned by Lambda.
+// It is captured at deploy time to restore the state by rollback.
+type functionSnapshot struct {
+	FunctionName string                `json:"FunctionName"`
+	Version      string                `json:"Version"`
+	CapturedAt   time.Time             `json:"CapturedAt"`
+	Tags         Tags                  `json:"Tags"`
+	FunctionURLs []*FunctionURL        `json:"FunctionURLs,omitempty"`
+	Permissions  []*functionPermission `json:"Permissions,omitempty"`
+}
+
+// functionPermission represents a statement of the resource-based policy of the function.
+// The statements for function URLs (lambda:InvokeFunctionUrl) are captured in FunctionURLs.
+type functionPermission struct {
+	Qualifier        *string `json:"Qualifier,omitempty"`
+	StatementId      string  `json:"StatementId"`
+	Action           string  `json:"Action"`
+	Principal        *string `json:"Principal"`
+	SourceArn        *string `json:"SourceArn,omitempty"`
+	SourceAccount    *string `json:"SourceAccount,omitempty"`
+	PrincipalOrgID   *string `json:"PrincipalOrgID,omitempty"`
+	EventSourceToken *string `json:"EventSourceToken,omitempty"`
+}
+
+func newFunctionPermission(qualifier *string, s PolicyStatement) *functionPermission {
+	return &functionPermission{
+		Qualifier:        qualifier,
+		StatementId:      s.Sid,
+		Action:           s.Action,
+		Principal:        s.PrincipalString(),
+		SourceArn:        s.SourceArn(),
+		SourceAccount:    s.ConditionValue("StringEquals", "AWS:SourceAccount"),
+		PrincipalOrgID:   s.ConditionValue("StringEquals", "aws:PrincipalOrgID"),
+		EventSourceToken: s.ConditionValue("StringEquals", "lambda:EventSourceToken"),
+	}
+}
+
+func (p *functionPermission) addPermissionInput(name string) *lambda.AddPermissionInput {
+	return &lambda.AddPermissionInput{
+		FunctionName:     aws.String(name),
+		Qualifier:        p.Qualifier,
+		StatementId:      aws.String(p.StatementId),
+		Action:           aws.String(p.Action),
+		Principal:        p.Principal,
+		SourceArn:        p.SourceArn,
+		SourceAccount:    p.SourceAccount,
+		PrincipalOrgID:   p.PrincipalOrgID,
+		EventSourceToken: p.EventSourceToken,
+	}
+}
+
+// findPermissions returns the permissions for the qualifier in the snapshot
+func (s *functionSnapshot) findPermissions(qualifier *string) []*functionPermission {
+	var ps []*functionPermission
+	for _, p := range s.Permissions {
+		if aws.ToString(p.Qualifier) == aws.ToString(qualifier) {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// state returns the comparable state of the snapshot
+func (s *functionSnapshot) state() map[string]any {
+	urls := make(map[string]any, len(s.FunctionURLs))
+	for _, fu := range s.FunctionURLs {
+		urls[fullQualifiedFunctionName(s.FunctionName, fu.Config.Qualifier)] = fu
+	}
+	permissions := make(map[string]map[string]any)
+	for _, p := range s.Permissions {
+		name := fullQualifiedFunctionName(s.FunctionName, p.Qualifier)
+		if permissions[name] == nil {
+			permissions[name] = make(map[string]any)
+		}
+		permissions[name][p.StatementId] = p
+	}
+	tags := s.Tags
+	if tags == nil {
+		tags = Tags{}
+	}
+	return map[string]any{
+		"Tags":         tags,
+		"FunctionURLs": urls,
+		"Permissions":  permissions,
+	}
+}
+
+// findFunctionURL returns the function URL for the qualifier in the snapshot
+func (s *functionSnapshot) findFunctionURL(qualifier *string) *FunctionURL {
+	for _, fu := range s.FunctionURLs {
+		if aws.ToString(fu.Config.Qualifier) == aws.ToString(qualifier) {
+			return fu
+		}
+	}
+	return nil
+}
+
+// snapshotQualifiers returns qualifiers of the function URLs to capture
+func snapshotQualifiers(alias string) []*string {
+	qs := []*string{nil}
+	if alias != "" {
+		qs = append(qs, aws.String(alias))
+	}
+	return qs
+}
+
+// captureSnapshot captures tags, function URLs and permissions (for the qualifiers) of the function
+func (app *App) captureSnapshot(ctx context.Context, name, version string, qualifiers []*string) (*functionSnapshot, error) {
+	snap := &functionSnapshot{
+		FunctionName: name,
+		Version:      version,
+		CapturedAt:   time.Now(),
+	}
+	arn := app.functionArn(ctx, name)
+	res, err := app.lambda.ListTags(ctx, &lambda.ListTagsInput{
+		Resource: aws.String(arn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", arn, err)
+	}
+	snap.Tags = res.Tags
+
+	for _, q := range qualifiers {
+		statements, err := app.getPolicyStatements(ctx, name, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range statements {
+			if st.Action == "lambda:InvokeFunctionUrl" {
+				// captured with the function url
+				continue
+			}
+			snap.Permissions = append(snap.Permissions, newFunctionPermission(q, st))
+		}
+	}
+	sort.SliceStable(snap.Permissions, func(i, j int) bool {
+		return snap.Permissions[i].StatementId < snap.Permissions[j].StatementId
+	})
+
+	for _, q := range qualifiers {
+		fc, err := app.lambda.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+			FunctionName: aws.String(name),
+			Qualifier:    q,
+		})
+		if err != nil {
+			var nfe *types.ResourceNotFoundException
+			if errors.As(err, &nfe) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get function url config: %w", err)
+		}
+		ps, err := app.getFunctionURLPermissions(ctx, name, q)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(ps, func(i, j int) bool { return ps[i].Sid() < ps[j].Sid() })
+		snap.FunctionURLs = append(snap.FunctionURLs, &FunctionURL{
+			Config: &FunctionURLConfig{
+				FunctionName: aws.String(name),
+				Qualifier:    q,
+				AuthType:     fc.AuthType,
+				Cors:         fc.Cors,
+				InvokeMode:   fc.InvokeMode,
+			},
+			Permissions: ps,
+		})
+	}
+	return snap, nil
+}
+
+// saveSnapshotAfterDeploy captures and saves the snapshot of the deployed version.
+// Failures are logged as warnings, because the function is already deployed.
+func (app *App) saveSnapshotAfterDeploy(ctx context.Context, opt *DeployOption, name, version string) {
+	if opt.Snapshot == "" || opt.DryRun {
+		return
+	}
+	if version == versionLatest || version == "" {
+		log.Printf("[warn] snapshot is not saved for unpublished version")
+		return
+	}
+	snap, err := app.captureSnapshot(ctx, name, version, snapshotQualifiers(opt.AliasName))
+	if err != nil {
+		log.Printf("[warn] failed to capture snapshot of version %s (deployed successfully): %s", version, err)
+		return
+	}
+	if err := app.saveSnapshot(ctx, opt.Snapshot, snap); err != nil {
+		log.Printf("[warn] failed to save snapshot of version %s (deployed successfully): %s", version, err)
+	}
+}
+
+// snapshotPath returns the path of the snapshot in the location (local directory or s3://bucket/prefix)
+func snapshotPath(location, name, version string) (bucket string, key string, err error) {
+	if !strings.HasPrefix(location, "s3://") {
+		return "", filepath.Join(location, name, version+".json"), nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid snapshot location %s: %w", location, err)
+	}
+	return u.Host, path.Join(strings.TrimPrefix(u.Path, "/"), name, version+".json"), nil
+}
+
+func (app *App) saveSnapshot(ctx context.Context, location string, snap *functionSnapshot) error {
+	b, err := marshalJSON(snap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	bucket, key, err := snapshotPath(location, snap.FunctionName, snap.Version)
+	if err != nil {
+		return err
+	}
+	if bucket == "" {
+		if err := os.MkdirAll(filepath.Dir(key), 0755); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		if err := os.WriteFile(key, b, 0644); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		log.Printf("[info] saved snapshot of version %s to %s", snap.Version, key)
+		return nil
+	}
+	svc := s3.NewFromConfig(app.awsConfig)
+	if _, err := svc.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(b),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return fmt.Errorf("failed to put snapshot to s3://%s/%s: %w", bucket, key, err)
+	}
+	log.Printf("[info] saved snapshot of version %s to s3://%s/%s", snap.Version, bucket, key)
+	return nil
+}
+
+func (app *App) loadSnapshot(ctx context.Context, location, name, version string) (*functionSnapshot, error) {
+	bucket, key, err := snapshotPath(location, name, version)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	if bucket == "" {
+		if b, err = os.ReadFile(key); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+	} else {
+		svc := s3.NewFromConfig(app.awsConfig)
+		res, err := svc.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get snapshot from s3://%s/%s: %w", bucket, key, err)
+		}
+		defer res.Body.Close()
+		if b, err = io.ReadAll(res.Body); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+	}
+	var snap functionSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	if snap.FunctionName != name || snap.Version != version {
+		return nil, fmt.Errorf("snapshot is for %s:%s, not for %s:%s", snap.FunctionName, snap.Version, name, version)
+	}
+	return &snap, nil
+}
+
+// diffSnapshot returns the diff from the current state to the snapshot
+func diffSnapshot(current, snap *functionSnapshot) (string, error) {
+	c, _ := marshalAny(current.state())
+	s, _ := marshalAny(snap.state())
+	ds, err := jsondiff.Diff(
+		&jsondiff.Input{Name: "current", X: c},
+		&jsondiff.Input{Name: "snapshot of version " + snap.Version, X: s},
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff: %w", err)
+	}
+	return ds, nil
+}
+
+// restoreSnapshot restores tags, permissions and function URLs of the function to the snapshot.
+// The diff to restore is printed before restoring.
+func (app *App) restoreSnapshot(ctx context.Context, snap *functionSnapshot, alias string, dryRun bool) error {
+	name := snap.FunctionName
+	qualifiers := snapshotQualifiers(alias)
+	for _, fu := range snap.FunctionURLs {
+		if fu.Config.Qualifier != nil && aws.ToString(fu.Config.Qualifier) != alias {
+			qualifiers = append(qualifiers, fu.Config.Qualifier)
+		}
+	}
+	current, err := app.captureSnapshot(ctx, name, "", qualifiers)
+	if err != nil {
+		return fmt.Errorf("failed to capture current state: %w", err)
+	}
+	ds, err := diffSnapshot(current, snap)
+	if err != nil {
+		return err
+	}
+	if ds == "" {
+		log.Printf("[info] no changes to restore from the snapshot of version %s", snap.Version)
+		return nil
+	}
+	fmt.Print(coloredDiff(ds))
+
+	opt := &DeployOption{DryRun: dryRun}
+	log.Printf("[info] restoring tags, permissions and function urls from the snapshot of version %s %s", snap.Version, opt.label())
+	if err := app.syncTags(ctx, app.functionArn(ctx, name), current.Tags, snap.Tags, opt); err != nil {
+		return err
+	}
+	for _, q := range qualifiers {
+		if err := app.restorePermissions(ctx, name, current.findPermissions(q), snap.findPermissions(q), opt); err != nil {
+			return err
+		}
+	}
+	for _, q := range qualifiers {
+		if fu := snap.findFunctionURL(q); fu != nil {
+			if err := app.deployFunctionURL(ctx, fu, opt); err != nil {
+				return err
+			}
+		} else if fu := current.findFunctionURL(q); fu != nil {
+			if err := app.deleteFunctionURL(ctx, fu, opt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restorePermissions adds and removes the statements of the resource-based policy from the current to the snapshot.
+// A statement changed from the current is removed and added again.
+func (app *App) restorePermissions(ctx context.Context, name string, current, snap []*functionPermission, opt *DeployOption) error {
+	var adds, removes []*functionPermission
+	for _, p := range snap {
+		if c := findPermission(current, p.StatementId); c == nil || jsonStr(c) != jsonStr(p) {
+			adds = append(adds, p)
+		}
+	}
+	for _, c := range current {
+		if p := findPermission(snap, c.StatementId); p == nil || jsonStr(c) != jsonStr(p) {
+			removes = append(removes, c)
+		}
+	}
+	if len(adds) == 0 && len(removes) == 0 {
+		return nil
+	}
+
+	log.Printf("[info] removing %d permissions %s", len(removes), opt.label())
+	if !opt.DryRun {
+		for _, p := range removes {
+			if _, err := app.lambda.RemovePermission(ctx, &lambda.RemovePermissionInput{
+				FunctionName: aws.String(name),
+				Qualifier:    p.Qualifier,
+				StatementId:  aws.String(p.StatementId),
+			}); err != nil {
+				return fmt.Errorf("failed to remove permission: %w", err)
+			}
+			log.Printf("[info] removed permission Sid: %s", p.StatementId)
+		}
+	}
+
+	log.Printf("[info] adding %d permissions %s", len(adds), opt.label())
+	if !opt.DryRun {
+		for _, p := range adds {
+			if _, err := app.lambda.AddPermission(ctx, p.addPermissionInput(name)); err != nil {
+				return fmt.Errorf("failed to add permission: %w", err)
+			}
+			log.Printf("[info] added permission Sid: %s", p.StatementId)
+		}
+	}
+	return nil
+}
+
+func findPermission(ps []*functionPermission, sid string) *functionPermission {
+	for _, p := range ps {
+		if p.StatementId == sid {
+			return p
+		}
+	}
+	return nil
+}
+
+// deleteFunctionURL deletes the function url config and the permissions for it
+func (app *App) deleteFunctionURL(ctx context.Context, fu *FunctionURL, opt *DeployOption) error {
+	fqName := fullQualifiedFunctionName(*fu.Config.FunctionName, fu.Config.Qualifier)
+	log.Printf("[info] deleting function url config for %s %s", fqName, opt.label())
+	if opt.DryRun {
+		return nil
+	}
+	if _, err := app.lambda.DeleteFunctionUrlConfig(ctx, &lambda.DeleteFunctionUrlConfigInput{
+		FunctionName: fu.Config.FunctionName,
+		Qualifier:    fu.Config.Qualifier,
+	}); err != nil {
+		return fmt.Errorf("failed to delete function url config: %w", err)
+	}
+	for _, p := range fu.Permissions {
+		if _, err := app.lambda.RemovePermission(ctx, fu.RemovePermissionInput(p.Sid())); err != nil {
+			return fmt.Errorf("failed to remove permission: %w", err)
+		}
+		log.Printf("[info] removed permission Sid: %s", p.Sid())
+	}
+	return nil
+}
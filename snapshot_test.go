@@ -0,0 +1,247 @@
+package lambroll_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testSnapshotPaths = []struct {
+	location string
+	bucket   string
+	key      string
+}{
+	{"snapshots", "", filepath.Join("snapshots", "hello", "12.json")},
+	{"s3://example-bucket/lambroll/snapshots/", "example-bucket", "lambroll/snapshots/hello/12.json"},
+	{"s3://example-bucket", "example-bucket", "hello/12.json"},
+}
+
+func TestSnapshotPath(t *testing.T) {
+	for _, c := range testSnapshotPaths {
+		bucket, key, err := lambroll.SnapshotPath(c.location, "hello", "12")
+		if err != nil {
+			t.Errorf("%s: %s", c.location, err)
+			continue
+		}
+		if bucket != c.bucket || key != c.key {
+			t.Errorf("%s: unexpected path %s %s", c.location, bucket, key)
+		}
+	}
+}
+
+func newTestSnapshot(version string) *lambroll.FunctionSnapshot {
+	return &lambroll.FunctionSnapshot{
+		FunctionName: "hello",
+		Version:      version,
+		CapturedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tags:         lambroll.Tags{"Env": "prod"},
+		FunctionURLs: []*lambroll.FunctionURL{
+			{
+				Config: &lambroll.FunctionURLConfig{
+					FunctionName: aws.String("hello"),
+					Qualifier:    aws.String("current"),
+					AuthType:     types.FunctionUrlAuthTypeNone,
+				},
+				Permissions: lambroll.FunctionURLPermissions{
+					{
+						AddPermissionInput: lambda.AddPermissionInput{
+							StatementId: aws.String("lambroll-0123"),
+							Principal:   aws.String("*"),
+						},
+					},
+				},
+			},
+		},
+		Permissions: []*lambroll.FunctionPermission{
+			{
+				StatementId: "events",
+				Action:      "lambda:InvokeFunction",
+				Principal:   aws.String("events.amazonaws.com"),
+			},
+			{
+				StatementId:   "s3",
+				Action:        "lambda:InvokeFunction",
+				Principal:     aws.String("s3.amazonaws.com"),
+				SourceArn:     aws.String("arn:aws:s3:::example-bucket"),
+				SourceAccount: aws.String("123456789012"),
+			},
+		},
+	}
+}
+
+func TestSnapshotSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	app := newTestApp(t, nil)
+	dir := t.TempDir()
+	if err := app.SaveSnapshot(ctx, dir, newTestSnapshot("12")); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := app.LoadSnapshot(ctx, dir, "hello", "12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Tags["Env"] != "prod" {
+		t.Errorf("unexpected tags %v", snap.Tags)
+	}
+	if len(snap.FunctionURLs) != 1 {
+		t.Fatalf("unexpected function urls %d", len(snap.FunctionURLs))
+	}
+	fu := snap.FunctionURLs[0]
+	if aws.ToString(fu.Config.Qualifier) != "current" || fu.Config.AuthType != types.FunctionUrlAuthTypeNone {
+		t.Errorf("unexpected function url config %#v", fu.Config)
+	}
+	if sids := fu.Permissions.Sids(); len(sids) != 1 || sids[0] != "lambroll-0123" {
+		t.Errorf("unexpected permissions %v", sids)
+	}
+	if len(snap.Permissions) != 2 || aws.ToString(snap.Permissions[1].SourceArn) != "arn:aws:s3:::example-bucket" {
+		t.Errorf("unexpected permissions %v", snap.Permissions)
+	}
+	if _, err := app.LoadSnapshot(ctx, dir, "hello", "11"); err == nil {
+		t.Error("expected error for missing snapshot")
+	}
+}
+
+func TestDiffSnapshot(t *testing.T) {
+	current := newTestSnapshot("")
+	snap := newTestSnapshot("12")
+	if ds, err := lambroll.DiffSnapshot(current, snap); err != nil {
+		t.Fatal(err)
+	} else if ds != "" {
+		t.Errorf("unexpected diff for the same state %s", ds)
+	}
+
+	current.Tags["Env"] = "dev"
+	current.FunctionURLs[0].Config.AuthType = types.FunctionUrlAuthTypeAwsIam
+	current.Permissions = current.Permissions[:1]
+	ds, err := lambroll.DiffSnapshot(current, snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`-    "Env": "dev"`, `+    "Env": "prod"`, `-        "AuthType": "AWS_IAM"`, `+        "AuthType": "NONE"`, `+      "s3": {`, `+        "SourceArn": "arn:aws:s3:::example-bucket"`, "snapshot of version 12"} {
+		if !strings.Contains(ds, s) {
+			t.Errorf("diff should contain %q\n%s", s, ds)
+		}
+	}
+}
+
+// testRestoreSnapshotServer serves the current state of the function hello.
+// tags: Env=dev, Old=x
+// function url (unqualified): AWS_IAM with the permission lambroll-old
+// function url (current): AWS_IAM without permissions
+// permissions (unqualified): events (same as the snapshot) and sns (not in the snapshot)
+func testRestoreSnapshotServer(t *testing.T) (*httptest.Server, func() []string) {
+	const arn = "arn:aws:lambda:ap-northeast-1:123456789012:function:hello"
+	var mu sync.Mutex
+	var calls []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("Qualifier")
+		call := r.Method + " " + r.URL.Path
+		if q != "" {
+			call += "?Qualifier=" + q
+		}
+		if r.Method != http.MethodGet {
+			b, _ := io.ReadAll(r.Body)
+			if len(b) > 0 {
+				call += " " + string(b)
+			}
+			if keys := r.URL.Query()["tagKeys"]; len(keys) > 0 {
+				call += " " + strings.Join(keys, ",")
+			}
+			mu.Lock()
+			calls = append(calls, call)
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/2017-03-31/tags/"+arn:
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, `{"Tags":{"Env":"dev","Old":"x"}}`)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/2021-10-31/functions/hello/url":
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			fmt.Fprint(w, `{"AuthType":"AWS_IAM","FunctionUrl":"https://example.lambda-url.ap-northeast-1.on.aws/"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/2015-03-31/functions/hello/policy":
+			if q != "" {
+				w.Header().Set("X-Amzn-Errortype", "ResourceNotFoundException")
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"Type":"User","message":"The resource you requested does not exist."}`)
+				return
+			}
+			policy := `{"Version":"2012-10-17","Statement":[` +
+				`{"Sid":"lambroll-old","Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"lambda:InvokeFunctionUrl","Resource":"` + arn + `","Condition":{"StringEquals":{"lambda:FunctionUrlAuthType":"AWS_IAM"}}},` +
+				`{"Sid":"events","Effect":"Allow","Principal":{"Service":"events.amazonaws.com"},"Action":"lambda:InvokeFunction","Resource":"` + arn + `"},` +
+				`{"Sid":"sns","Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":"lambda:InvokeFunction","Resource":"` + arn + `","Condition":{"ArnLike":{"AWS:SourceArn":"arn:aws:sns:ap-northeast-1:123456789012:topic"}}}` +
+				`]}`
+			fmt.Fprintf(w, `{"Policy":%q}`, policy)
+		case r.Method == http.MethodPost && r.URL.Path == "/2015-03-31/functions/hello/policy":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"Statement":"{}"}`)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/2015-03-31/functions/hello/policy/"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s", call)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ts, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		t.Run(fmt.Sprintf("dry-run=%v", dryRun), func(t *testing.T) {
+			ts, calls := testRestoreSnapshotServer(t)
+			defer ts.Close()
+			ctx := context.Background()
+			app := newTestApp(t, ts)
+			app.CallerIdentity().Resolver = func(_ context.Context) (*sts.GetCallerIdentityOutput, error) {
+				return &sts.GetCallerIdentityOutput{
+					Account: aws.String("123456789012"),
+					Arn:     aws.String("arn:aws:iam::123456789012:user/test-user"),
+					UserId:  aws.String("AIDXXXXXXXXXXXXXXXXXX"),
+				}, nil
+			}
+			if err := app.RestoreSnapshot(ctx, newTestSnapshot("12"), "current", dryRun); err != nil {
+				t.Fatal(err)
+			}
+			var expected []string
+			if !dryRun {
+				expected = []string{
+					`POST /2017-03-31/tags/arn:aws:lambda:ap-northeast-1:123456789012:function:hello {"Tags":{"Env":"prod"}}`,
+					`DELETE /2017-03-31/tags/arn:aws:lambda:ap-northeast-1:123456789012:function:hello Old`,
+					`DELETE /2015-03-31/functions/hello/policy/sns`,
+					`POST /2015-03-31/functions/hello/policy {"Action":"lambda:InvokeFunction","Principal":"s3.amazonaws.com","SourceAccount":"123456789012","SourceArn":"arn:aws:s3:::example-bucket","StatementId":"s3"}`,
+					`DELETE /2021-10-31/functions/hello/url`,
+					`DELETE /2015-03-31/functions/hello/policy/lambroll-old`,
+					`PUT /2021-10-31/functions/hello/url?Qualifier=current {"AuthType":"NONE"}`,
+					`POST /2015-03-31/functions/hello/policy?Qualifier=current {"Action":"lambda:InvokeFunctionUrl","FunctionUrlAuthType":"NONE","Principal":"*","StatementId":"lambroll-0123"}`,
+				}
+			}
+			if d := cmp.Diff(expected, calls()); d != "" {
+				t.Errorf("unexpected calls (-expected +got)\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package lambroll
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsssm "github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/fujiwara/ssm-lookup/ssm"
+	"github.com/google/go-jsonnet"
+)
+
+// ssmLookup wraps ssm-lookup functions to register values of SecureString parameters to the masker.
+type ssmLookup struct {
+	app    *ssm.App
+	cache  *sync.Map
+	masker *masker
+}
+
+func newSSMLookup(cfg aws.Config, m *masker) *ssmLookup {
+	cache := &sync.Map{}
+	return &ssmLookup{
+		app:    ssm.New(cfg, cache),
+		cache:  cache,
+		masker: m,
+	}
+}
+
+// registerSecure registers the value to the masker if the parameter is SecureString.
+func (s *ssmLookup) registerSecure(name string, value any) {
+	v, ok := s.cache.Load(name)
+	if !ok {
+		return
+	}
+	if p, ok := v.(*awsssm.GetParameterOutput); ok && p.Parameter != nil && p.Parameter.Type == ssmtypes.ParameterTypeSecureString {
+		if str, ok := value.(string); ok {
+			// ssm-secure dynamic references are not supported in the environment variables of Lambda functions
+			s.masker.add(str, "")
+		}
+	}
+}
+
+func (s *ssmLookup) FuncMap(ctx context.Context) template.FuncMap {
+	return template.FuncMap{
+		"ssm": func(paramName string, index ...int) (string, error) {
+			value, err := s.app.Lookup(ctx, paramName, index...)
+			if err != nil {
+				return "", fmt.Errorf("failed to lookup ssm parameter: %w", err)
+			}
+			s.registerSecure(paramName, value)
+			return value, nil
+		},
+	}
+}
+
+func (s *ssmLookup) JsonnetNativeFuncs(ctx context.Context) []*jsonnet.NativeFunction {
+	funcs := s.app.JsonnetNativeFuncs(ctx)
+	for _, f := range funcs {
+		lookup := f.Func
+		f.Func = func(p []any) (any, error) {
+			value, err := lookup(p)
+			if err != nil {
+				return nil, err
+			}
+			if name, ok := p[0].(string); ok {
+				s.registerSecure(name, value)
+			}
+			return value, nil
+		}
+	}
+	return funcs
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"math"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -19,19 +22,68 @@ type StatusOption struct {
 }
 
 type StatusOutput struct {
-	FunctionName    string `json:"FunctionName"`
-	FunctionArn     string `json:"FunctionArn"`
-	Version         string `json:"Version"`
-	Runtime         string `json:"Runtime,omitempty"`
-	PackageType     string `json:"PackageType"`
-	State           string `json:"State"`
-	LastUpdateState string `json:"LastUpdateState"`
-	FunctionURL     string `json:"FunctionURL,omitempty"`
+	FunctionName           string               `json:"FunctionName"`
+	FunctionArn            string               `json:"FunctionArn"`
+	Version                string               `json:"Version"`
+	Runtime                string               `json:"Runtime,omitempty"`
+	PackageType            string               `json:"PackageType"`
+	Architectures          []string             `json:"Architectures,omitempty"`
+	MemorySize             int32                `json:"MemorySize"`
+	Timeout                int32                `json:"Timeout"`
+	CodeSize               int64                `json:"CodeSize"`
+	LastModified           string               `json:"LastModified"`
+	Layers                 []string             `json:"Layers,omitempty"`
+	State                  string               `json:"State"`
+	LastUpdateState        string               `json:"LastUpdateState"`
+	LastUpdateStatusReason string               `json:"LastUpdateStatusReason,omitempty"`
+	ReservedConcurrency    *int32               `json:"ReservedConcurrency,omitempty"`
+	ProvisionedConcurrency []*StatusProvisioned `json:"ProvisionedConcurrency,omitempty"`
+	Aliases                []*StatusAlias       `json:"Aliases,omitempty"`
+	FunctionURL            string               `json:"FunctionURL,omitempty"`
+	FunctionURLAuthType    string               `json:"FunctionURLAuthType,omitempty"`
+}
+
+// StatusAlias represents an alias and its routing configuration
+type StatusAlias struct {
+	Name            string             `json:"Name"`
+	FunctionVersion string             `json:"FunctionVersion"`
+	RoutingWeights  map[string]float64 `json:"RoutingWeights,omitempty"`
+}
+
+func (a *StatusAlias) String() string {
+	s := a.Name + " -> " + a.FunctionVersion
+	if len(a.RoutingWeights) == 0 {
+		return s
+	}
+	versions := make([]string, 0, len(a.RoutingWeights))
+	for v := range a.RoutingWeights {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	weights := make([]string, 0, len(versions))
+	for _, v := range versions {
+		weights = append(weights, fmt.Sprintf("%s: %g%%", v, math.Round(a.RoutingWeights[v]*10000)/100))
+	}
+	return s + " (" + strings.Join(weights, ", ") + ")"
+}
+
+// StatusProvisioned represents a provisioned concurrency config
+type StatusProvisioned struct {
+	Qualifier string `json:"Qualifier"`
+	Requested int32  `json:"Requested"`
+	Allocated int32  `json:"Allocated"`
+	Available int32  `json:"Available"`
+	Status    string `json:"Status"`
+}
+
+func (p *StatusProvisioned) String() string {
+	return fmt.Sprintf("%s: %d/%d available (%s)", p.Qualifier, p.Available, p.Requested, p.Status)
 }
 
 func (o *StatusOutput) String() string {
 	buf := new(strings.Builder)
 	w := tablewriter.NewWriter(buf)
+	w.SetAutoWrapText(false)
 	w.Append([]string{"FunctionName", o.FunctionName})
 	w.Append([]string{"FunctionArn", o.FunctionArn})
 	w.Append([]string{"Version", o.Version})
@@ -39,10 +91,41 @@ func (o *StatusOutput) String() string {
 		w.Append([]string{"Runtime", o.Runtime})
 	}
 	w.Append([]string{"PackageType", o.PackageType})
+	if len(o.Architectures) > 0 {
+		w.Append([]string{"Architectures", strings.Join(o.Architectures, ",")})
+	}
+	w.Append([]string{"MemorySize", fmt.Sprintf("%d MB", o.MemorySize)})
+	w.Append([]string{"Timeout", fmt.Sprintf("%d sec", o.Timeout)})
+	w.Append([]string{"CodeSize", fmt.Sprintf("%d bytes", o.CodeSize)})
+	w.Append([]string{"LastModified", o.LastModified})
+	if len(o.Layers) > 0 {
+		w.Append([]string{"Layers", strings.Join(o.Layers, "\n")})
+	}
 	w.Append([]string{"State", o.State})
 	w.Append([]string{"LastUpdateState", o.LastUpdateState})
+	if o.LastUpdateStatusReason != "" {
+		w.Append([]string{"LastUpdateStatusReason", o.LastUpdateStatusReason})
+	}
+	if o.ReservedConcurrency != nil {
+		w.Append([]string{"ReservedConcurrency", fmt.Sprintf("%d", *o.ReservedConcurrency)})
+	}
+	if len(o.ProvisionedConcurrency) > 0 {
+		pcs := make([]string, 0, len(o.ProvisionedConcurrency))
+		for _, pc := range o.ProvisionedConcurrency {
+			pcs = append(pcs, pc.String())
+		}
+		w.Append([]string{"ProvisionedConcurrency", strings.Join(pcs, "\n")})
+	}
+	if len(o.Aliases) > 0 {
+		aliases := make([]string, 0, len(o.Aliases))
+		for _, a := range o.Aliases {
+			aliases = append(aliases, a.String())
+		}
+		w.Append([]string{"Aliases", strings.Join(aliases, "\n")})
+	}
 	if o.FunctionURL != "" {
 		w.Append([]string{"FunctionURL", o.FunctionURL})
+		w.Append([]string{"FunctionURLAuthType", o.FunctionURLAuthType})
 	}
 	w.Render()
 	return buf.String()
@@ -63,15 +146,21 @@ func (app *App) Status(ctx context.Context, opt *StatusOption) error {
 	if err != nil {
 		return fmt.Errorf("failed to GetFunction %s: %w", name, err)
 	}
-	out := &StatusOutput{
-		FunctionName:    aws.ToString(res.Configuration.FunctionName),
-		FunctionArn:     aws.ToString(res.Configuration.FunctionArn),
-		Version:         aws.ToString(res.Configuration.Version),
-		Runtime:         string(res.Configuration.Runtime),
-		PackageType:     string(res.Configuration.PackageType),
-		State:           string(res.Configuration.State),
-		LastUpdateState: string(res.Configuration.LastUpdateStatus),
+	out := newStatusOutput(res)
+
+	aliases, err := app.listAliases(ctx, name)
+	if err != nil {
+		return err
 	}
+	out.Aliases = newStatusAliases(aliases)
+
+	if pcs, err := app.listProvisionedConcurrency(ctx, name); err != nil {
+		// optional information. e.g. lambda:ListProvisionedConcurrencyConfigs may not be allowed
+		log.Printf("[warn] %s", err)
+	} else {
+		out.ProvisionedConcurrency = pcs
+	}
+
 	if res, err := app.lambda.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
 		FunctionName: &name,
 		Qualifier:    opt.Qualifier,
@@ -84,6 +173,7 @@ func (app *App) Status(ctx context.Context, opt *StatusOption) error {
 		}
 	} else {
 		out.FunctionURL = aws.ToString(res.FunctionUrl)
+		out.FunctionURLAuthType = string(res.AuthType)
 	}
 	switch opt.Output {
 	case "table":
@@ -94,3 +184,80 @@ func (app *App) Status(ctx context.Context, opt *StatusOption) error {
 	}
 	return nil
 }
+
+func newStatusOutput(res *lambda.GetFunctionOutput) *StatusOutput {
+	c := res.Configuration
+	out := &StatusOutput{
+		FunctionName:           aws.ToString(c.FunctionName),
+		FunctionArn:            aws.ToString(c.FunctionArn),
+		Version:                aws.ToString(c.Version),
+		Runtime:                string(c.Runtime),
+		PackageType:            string(c.PackageType),
+		MemorySize:             aws.ToInt32(c.MemorySize),
+		Timeout:                aws.ToInt32(c.Timeout),
+		CodeSize:               c.CodeSize,
+		LastModified:           aws.ToString(c.LastModified),
+		State:                  string(c.State),
+		LastUpdateState:        string(c.LastUpdateStatus),
+		LastUpdateStatusReason: aws.ToString(c.LastUpdateStatusReason),
+	}
+	for _, a := range c.Architectures {
+		out.Architectures = append(out.Architectures, string(a))
+	}
+	for _, l := range c.Layers {
+		out.Layers = append(out.Layers, aws.ToString(l.Arn))
+	}
+	if res.Concurrency != nil {
+		out.ReservedConcurrency = res.Concurrency.ReservedConcurrentExecutions
+	}
+	return out
+}
+
+func newStatusAliases(aliases []types.AliasConfiguration) []*StatusAlias {
+	var res []*StatusAlias
+	for _, alias := range aliases {
+		a := &StatusAlias{
+			Name:            aws.ToString(alias.Name),
+			FunctionVersion: aws.ToString(alias.FunctionVersion),
+		}
+		if alias.RoutingConfig != nil && len(alias.RoutingConfig.AdditionalVersionWeights) > 0 {
+			a.RoutingWeights = alias.RoutingConfig.AdditionalVersionWeights
+		}
+		res = append(res, a)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func (app *App) listProvisionedConcurrency(ctx context.Context, name string) ([]*StatusProvisioned, error) {
+	var pcs []*StatusProvisioned
+	var marker *string
+	for {
+		res, err := app.lambda.ListProvisionedConcurrencyConfigs(ctx, &lambda.ListProvisionedConcurrencyConfigsInput{
+			FunctionName: &name,
+			Marker:       marker,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list provisioned concurrency configs: %w", err)
+		}
+		for _, c := range res.ProvisionedConcurrencyConfigs {
+			qualifier := aws.ToString(c.FunctionArn)
+			if i := strings.LastIndex(qualifier, ":"); i >= 0 {
+				qualifier = qualifier[i+1:]
+			}
+			pcs = append(pcs, &StatusProvisioned{
+				Qualifier: qualifier,
+				Requested: aws.ToInt32(c.RequestedProvisionedConcurrentExecutions),
+				Allocated: aws.ToInt32(c.AllocatedProvisionedConcurrentExecutions),
+				Available: aws.ToInt32(c.AvailableProvisionedConcurrentExecutions),
+				Status:    string(c.Status),
+			})
+		}
+		if marker = res.NextMarker; marker == nil {
+			break
+		}
+	}
+	return pcs, nil
+}
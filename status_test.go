@@ -0,0 +1,98 @@
+package lambroll_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/fujiwara/lambroll"
+)
+
+func TestStatusOutput(t *testing.T) {
+	out := lambroll.NewStatusOutput(&lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName:           aws.String("hello"),
+			FunctionArn:            aws.String("arn:aws:lambda:ap-northeast-1:123456789012:function:hello"),
+			Version:                aws.String("$LATEST"),
+			Runtime:                types.RuntimeProvidedal2023,
+			PackageType:            types.PackageTypeZip,
+			Architectures:          []types.Architecture{types.ArchitectureArm64},
+			MemorySize:             aws.Int32(256),
+			Timeout:                aws.Int32(30),
+			CodeSize:               1024,
+			LastModified:           aws.String("2024-01-02T03:04:05.000+0000"),
+			Layers:                 []types.Layer{{Arn: aws.String("arn:aws:lambda:ap-northeast-1:123456789012:layer:foo:3")}},
+			State:                  types.StateActive,
+			LastUpdateStatus:       types.LastUpdateStatusFailed,
+			LastUpdateStatusReason: aws.String("something wrong"),
+		},
+		Concurrency: &types.Concurrency{ReservedConcurrentExecutions: aws.Int32(10)},
+	})
+	out.Aliases = lambroll.NewStatusAliases([]types.AliasConfiguration{
+		{Name: aws.String("prod"), FunctionVersion: aws.String("3"), RoutingConfig: &types.AliasRoutingConfiguration{
+			AdditionalVersionWeights: map[string]float64{"4": 0.07},
+		}},
+		{Name: aws.String("current"), FunctionVersion: aws.String("4")},
+	})
+	s := out.String()
+	for _, expected := range []string{
+		"arm64",
+		"256 MB",
+		"30 sec",
+		"1024 bytes",
+		"2024-01-02T03:04:05.000+0000",
+		"arn:aws:lambda:ap-northeast-1:123456789012:layer:foo:3",
+		"something wrong",
+		"ReservedConcurrency",
+		"current -> 4",
+		"prod -> 3 (4: 7%)",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("status output should contain %q\n%s", expected, s)
+		}
+	}
+	if out.Aliases[0].Name != "current" {
+		t.Errorf("aliases must be sorted by name: %v", out.Aliases[0].Name)
+	}
+}
+
+func TestStatusWithoutProvisionedConcurrencyPermission(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/2015-03-31/functions/hello":
+			fmt.Fprint(w, `{"Configuration":{"FunctionName":"hello","Version":"$LATEST","Runtime":"nodejs20.x"}}`)
+		case "/2015-03-31/functions/hello/aliases":
+			fmt.Fprint(w, `{"Aliases":[{"Name":"current","FunctionVersion":"3"}]}`)
+		case "/2019-09-30/functions/hello/provisioned-concurrency":
+			w.Header().Set("X-Amzn-Errortype", "AccessDeniedException")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"Type":"User","message":"not authorized to perform: lambda:ListProvisionedConcurrencyConfigs"}`)
+		case "/2021-10-31/functions/hello/url":
+			w.Header().Set("X-Amzn-Errortype", "ResourceNotFoundException")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"Type":"User","message":"not found"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	fnPath := filepath.Join(t.TempDir(), "function.json")
+	if err := os.WriteFile(fnPath, []byte(`{"FunctionName":"hello"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app := newTestAppWithOption(t, ts, &lambroll.Option{Function: fnPath})
+	if err := app.Status(context.Background(), &lambroll.StatusOption{Output: "json"}); err != nil {
+		t.Errorf("status must not fail without the permission to list provisioned concurrency: %s", err)
+	}
+}
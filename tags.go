@@ -29,8 +29,12 @@ func (app *App) updateTags(ctx context.Context, fn *Function, opt *DeployOption)
 		}
 	}
 	log.Printf("[debug] %d tags found", len(tags.Tags))
+	return app.syncTags(ctx, arn, tags.Tags, fn.Tags, opt)
+}
 
-	setTags, removeTagKeys := mergeTags(tags.Tags, fn.Tags)
+// syncTags updates tags of the resource from oldTags to newTags
+func (app *App) syncTags(ctx context.Context, arn string, oldTags, newTags Tags, opt *DeployOption) error {
+	setTags, removeTagKeys := mergeTags(oldTags, newTags)
 
 	if len(setTags) == 0 && len(removeTagKeys) == 0 {
 		log.Println("[debug] no need to update tags (unchanged)")
@@ -40,7 +44,7 @@ func (app *App) updateTags(ctx context.Context, fn *Function, opt *DeployOption)
 	if n := len(setTags); n > 0 {
 		log.Printf("[info] setting %d tags %s", n, opt.label())
 		if !opt.DryRun {
-			_, err = app.lambda.TagResource(ctx, &lambda.TagResourceInput{
+			_, err := app.lambda.TagResource(ctx, &lambda.TagResourceInput{
 				Resource: aws.String(arn),
 				Tags:     setTags,
 			})
@@ -53,7 +57,7 @@ func (app *App) updateTags(ctx context.Context, fn *Function, opt *DeployOption)
 	if n := len(removeTagKeys); n > 0 {
 		log.Printf("[info] removing %d tags %s", n, opt.label())
 		if !opt.DryRun {
-			_, err = app.lambda.UntagResource(ctx, &lambda.UntagResourceInput{
+			_, err := app.lambda.UntagResource(ctx, &lambda.UntagResourceInput{
 				Resource: aws.String(arn),
 				TagKeys:  removeTagKeys,
 			})
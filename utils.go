@@ -58,6 +58,28 @@ func marshalJSON(s interface{}) ([]byte, error) {
 	}
 }
 
+// marshalJSONMasked marshals s as same as marshalJSON, but secret values are masked
+func (app *App) marshalJSONMasked(s interface{}) ([]byte, error) {
+	x, err := marshalAny(s)
+	if err != nil {
+		return nil, err
+	}
+	if b, err := json.MarshalIndent(app.masker.maskAny(x), "", "  "); err != nil {
+		return nil, err
+	} else {
+		return append(b, '\n'), nil
+	}
+}
+
+// jsonStrMasked returns indented JSON string of s with masked secret values
+func (app *App) jsonStrMasked(s any) string {
+	b, err := app.marshalJSONMasked(s)
+	if err != nil {
+		log.Printf("[warn] failed to marshal json: %s", err)
+	}
+	return string(b)
+}
+
 func marshalAny(s interface{}) (interface{}, error) {
 	b, err := marshalJSON(s)
 	if err != nil {
@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +17,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 )
 
+// VersionsCommandOption represents subcommands of versions
+type VersionsCommandOption struct {
+	List  *VersionsOption      `cmd:"list" default:"withargs" help:"show versions of function (default)"`
+	Prune *VersionsPruneOption `cmd:"prune" help:"delete old versions of function"`
+	Diff  *VersionsDiffOption  `cmd:"diff" help:"show differences between two versions of function"`
+}
+
 // VersionsOption represents options for Versions()
 type VersionsOption struct {
 	Output       string `default:"table" enum:"table,json,tsv" help:"output format (table,json,tsv)"`
@@ -87,28 +96,17 @@ func (app *App) Versions(ctx context.Context, opt *VersionsOption) error {
 		return fmt.Errorf("failed to get aliases: %w", err)
 	}
 
-	var versions []types.FunctionConfiguration
-	var nextMarker *string
-	for {
-		res, err := app.lambda.ListVersionsByFunction(ctx, &lambda.ListVersionsByFunctionInput{
-			FunctionName: &name,
-			Marker:       nextMarker,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to list versions: %w", err)
-		}
-		versions = append(versions, res.Versions...)
-		if nextMarker = res.NextMarker; nextMarker == nil {
-			break
-		}
+	versions, err := app.listVersions(ctx, name)
+	if err != nil {
+		return err
 	}
 
 	vos := make(versionsOutputs, 0, len(versions))
 	var latestVo versionsOutput
 	for _, v := range versions {
-		lm, err := time.Parse("2006-01-02T15:04:05.999-0700", *v.LastModified)
+		lm, err := parseLastModified(*v.LastModified)
 		if err != nil {
-			return fmt.Errorf("failed to parse last modified: %w", err)
+			return err
 		}
 		vo := versionsOutput{
 			Version:      *v.Version,
@@ -141,7 +139,86 @@ func (app *App) Versions(ctx context.Context, opt *VersionsOption) error {
 }
 
 func (app *App) getAliases(ctx context.Context, name string) (map[string][]string, error) {
+	res, err := app.listAliases(ctx, name)
+	if err != nil {
+		return nil, err
+	}
 	aliases := make(map[string][]string)
+	for _, alias := range res {
+		aliases[*alias.FunctionVersion] = append(aliases[*alias.FunctionVersion], *alias.Name)
+		if alias.RoutingConfig == nil || alias.RoutingConfig.AdditionalVersionWeights == nil {
+			continue
+		}
+		for v := range alias.RoutingConfig.AdditionalVersionWeights {
+			aliases[v] = append(aliases[v], *alias.Name)
+		}
+	}
+	return aliases, nil
+}
+
+// parseLastModified parses LastModified of the function configuration
+func parseLastModified(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02T15:04:05.999-0700", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse last modified: %w", err)
+	}
+	return t, nil
+}
+
+// publishedVersion represents a published version of the function
+type publishedVersion struct {
+	Number       int64
+	Version      string
+	Description  string
+	LastModified time.Time
+}
+
+// newPublishedVersions returns published versions (except $LATEST) in descending order of the version number
+func newPublishedVersions(versions []types.FunctionConfiguration) ([]publishedVersion, error) {
+	published := make([]publishedVersion, 0, len(versions))
+	for _, v := range versions {
+		vs := aws.ToString(v.Version)
+		n, err := strconv.ParseInt(vs, 10, 64)
+		if err != nil {
+			continue // $LATEST
+		}
+		lm, err := parseLastModified(aws.ToString(v.LastModified))
+		if err != nil {
+			return nil, err
+		}
+		published = append(published, publishedVersion{
+			Number:       n,
+			Version:      vs,
+			Description:  aws.ToString(v.Description),
+			LastModified: lm,
+		})
+	}
+	sort.Slice(published, func(i, j int) bool { return published[i].Number > published[j].Number })
+	return published, nil
+}
+
+// listVersions lists all versions of the function including $LATEST
+func (app *App) listVersions(ctx context.Context, name string) ([]types.FunctionConfiguration, error) {
+	var versions []types.FunctionConfiguration
+	var nextMarker *string
+	for {
+		res, err := app.lambda.ListVersionsByFunction(ctx, &lambda.ListVersionsByFunctionInput{
+			FunctionName: &name,
+			Marker:       nextMarker,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions: %w", err)
+		}
+		versions = append(versions, res.Versions...)
+		if nextMarker = res.NextMarker; nextMarker == nil {
+			break
+		}
+	}
+	return versions, nil
+}
+
+func (app *App) listAliases(ctx context.Context, name string) ([]types.AliasConfiguration, error) {
+	var aliases []types.AliasConfiguration
 	var nextAliasMarker *string
 	for {
 		res, err := app.lambda.ListAliases(ctx, &lambda.ListAliasesInput{
@@ -151,15 +228,7 @@ func (app *App) getAliases(ctx context.Context, name string) (map[string][]strin
 		if err != nil {
 			return nil, fmt.Errorf("failed to list aliases: %w", err)
 		}
-		for _, alias := range res.Aliases {
-			aliases[*alias.FunctionVersion] = append(aliases[*alias.FunctionVersion], *alias.Name)
-			if alias.RoutingConfig == nil || alias.RoutingConfig.AdditionalVersionWeights == nil {
-				continue
-			}
-			for v := range alias.RoutingConfig.AdditionalVersionWeights {
-				aliases[v] = append(aliases[v], *alias.Name)
-			}
-		}
+		aliases = append(aliases, res.Aliases...)
 		if nextAliasMarker = res.NextMarker; nextAliasMarker == nil {
 			break
 		}
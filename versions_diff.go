@@ -0,0 +1,182 @@
+package lambroll
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aereal/jsondiff"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/fatih/color"
+	"github.com/kylelemons/godebug/diff"
+)
+
+// VersionsDiffOption represents options for DiffVersions()
+type VersionsDiffOption struct {
+	From     string `arg:"" help:"version or alias to compare from"`
+	To       string `arg:"" help:"version or alias to compare to"`
+	Code     bool   `help:"compare code packages file by file (Zip package type only)" default:"false"`
+	Ignore   string `help:"ignore diff by jq query" default:""`
+	ExitCode bool   `help:"exit with status 2 when there are differences" default:"false"`
+	Output   string `help:"output format (text,json,markdown)" default:"text" enum:"text,json,markdown"`
+}
+
+// publishedFunction represents the function of a version or an alias
+type publishedFunction struct {
+	Qualifier     string
+	Configuration *types.FunctionConfiguration
+	Code          *types.FunctionCodeLocation
+}
+
+// Label returns the qualifier with the version for an alias (e.g. current (version 45))
+func (f *publishedFunction) Label() string {
+	v := aws.ToString(f.Configuration.Version)
+	if v == f.Qualifier {
+		return fullQualifiedFunctionName(*f.Configuration.FunctionName, &f.Qualifier)
+	}
+	return fmt.Sprintf("%s (version %s)", fullQualifiedFunctionName(*f.Configuration.FunctionName, &f.Qualifier), v)
+}
+
+func (app *App) getPublishedFunction(ctx context.Context, name, qualifier string) (*publishedFunction, error) {
+	res, err := app.lambda.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+		Qualifier:    aws.String(qualifier),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get function %s: %w", fullQualifiedFunctionName(name, &qualifier), err)
+	}
+	return &publishedFunction{
+		Qualifier:     qualifier,
+		Configuration: res.Configuration,
+		Code:          res.Code,
+	}, nil
+}
+
+// DiffVersions shows the differences between two versions (or aliases) of the function
+func (app *App) DiffVersions(ctx context.Context, opt *VersionsDiffOption) error {
+	fn, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load function: %w", err)
+	}
+	report, texts, err := app.diffVersions(ctx, *fn.FunctionName, opt)
+	if err != nil {
+		return err
+	}
+	if err := printDiffReport(report, texts, opt.Output); err != nil {
+		return err
+	}
+	if opt.ExitCode && len(report.Changes) > 0 {
+		return &ExitCodeError{Code: 2}
+	}
+	return nil
+}
+
+func (app *App) diffVersions(ctx context.Context, name string, opt *VersionsDiffOption) (*diffReport, []string, error) {
+	from, err := app.getPublishedFunction(ctx, name, opt.From)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := app.getPublishedFunction(ctx, name, opt.To)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ds, changes, err := app.diffVersionsConfig(from, to, opt.Ignore)
+	if err != nil {
+		return nil, nil, err
+	}
+	report := &diffReport{FunctionName: name, Changes: append([]*diffChange{}, changes...)}
+	texts := []string{coloredDiff(ds)}
+
+	fromSha256, toSha256 := aws.ToString(from.Configuration.CodeSha256), aws.ToString(to.Configuration.CodeSha256)
+	prefix := "CodeSha256: "
+	if ds := diff.Diff(prefix+fromSha256, prefix+toSha256); ds != "" {
+		texts = append(texts,
+			color.RedString("---"+from.Label())+"\n"+
+				color.GreenString("+++"+to.Label())+"\n"+
+				coloredDiff(ds)+"\n",
+		)
+		report.Changes = append(report.Changes, &diffChange{
+			Path:     "CodeSha256",
+			Category: diffCategoryCode,
+			Old:      fromSha256,
+			New:      toSha256,
+		})
+		if opt.Code {
+			ts, changes, err := app.diffVersionsCode(from, to)
+			if err != nil {
+				return nil, nil, err
+			}
+			texts = append(texts, ts...)
+			report.Changes = append(report.Changes, changes...)
+		}
+	}
+	return report, texts, nil
+}
+
+// diffVersionsConfig returns the diff of configurations between the versions
+func (app *App) diffVersionsConfig(from, to *publishedFunction, ignore string) (string, []*diffChange, error) {
+	// tags are not versioned
+	fromFunc := newFunctionFrom(from.Configuration, from.Code, nil)
+	fillDefaultValues(fromFunc)
+	toFunc := newFunctionFrom(to.Configuration, to.Code, nil)
+	fillDefaultValues(toFunc)
+
+	fromJSON, _ := marshalAny(fromFunc)
+	toJSON, _ := marshalAny(toFunc)
+	fromJSON, toJSON = app.masker.maskAnyPair(fromJSON, toJSON)
+	values, err := ignoreByQuery(ignore, fromJSON, toJSON)
+	if err != nil {
+		return "", nil, err
+	}
+	fromJSON, toJSON = values[0], values[1]
+
+	ds, err := jsondiff.Diff(
+		&jsondiff.Input{Name: from.Label(), X: fromJSON},
+		&jsondiff.Input{Name: to.Label(), X: toJSON},
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to diff: %w", err)
+	}
+	return ds, compareValues("", fromJSON, toJSON, functionChangeCategory), nil
+}
+
+// diffVersionsCode compares the packages of the versions file by file
+func (app *App) diffVersionsCode(from, to *publishedFunction) ([]string, []*diffChange, error) {
+	for _, f := range []*publishedFunction{from, to} {
+		if f.Configuration.PackageType != types.PackageTypeZip || f.Code == nil || f.Code.Location == nil {
+			return nil, nil, fmt.Errorf("--code is only supported for Zip package type")
+		}
+	}
+	fromPath, err := downloadCode(*from.Code.Location)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(fromPath)
+	toPath, err := downloadCode(*to.Code.Location)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(toPath)
+
+	fromZip, err := zip.OpenReader(fromPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the package of %s: %w", from.Label(), err)
+	}
+	defer fromZip.Close()
+	toZip, err := zip.OpenReader(toPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the package of %s: %w", to.Label(), err)
+	}
+	defer toZip.Close()
+
+	files, err := diffCodeFiles(&fromZip.Reader, &toZip.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	texts, changes := codeFileChangesReport(files)
+	return texts, changes, nil
+}
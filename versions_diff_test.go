@@ -0,0 +1,114 @@
+package lambroll_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestZipBytes(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, body := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDiffVersions(t *testing.T) {
+	packages := map[string][]byte{
+		"41": newTestZipBytes(t, map[string]string{
+			"index.js": "exports.handler = async () => 'hello';\n",
+		}),
+		"45": newTestZipBytes(t, map[string]string{
+			"index.js":   "exports.handler = async () => 'world';\n",
+			"lib/new.js": "module.exports = {};\n",
+		}),
+	}
+	configurations := map[string]string{
+		"41": `{"FunctionName":"hello","Version":"41","Runtime":"nodejs20.x","Handler":"index.handler","MemorySize":128,"PackageType":"Zip","CodeSha256":"aaa","Environment":{"Variables":{"FOO":"1","DB_PASS":"old-pa55w0rd"}}}`,
+		"45": `{"FunctionName":"hello","Version":"45","Runtime":"nodejs20.x","Handler":"index.handler","MemorySize":256,"PackageType":"Zip","CodeSha256":"bbb","Environment":{"Variables":{"FOO":"2","DB_PASS":"n3w-pa55w0rd"}}}`,
+	}
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, ok := strings.CutPrefix(r.URL.Path, "/code/"); ok {
+			w.Write(packages[v])
+			return
+		}
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/functions/hello") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		v := r.URL.Query().Get("Qualifier")
+		if v == "current" {
+			v = "45"
+		}
+		conf, ok := configurations[v]
+		if !ok {
+			w.Header().Set("X-Amzn-Errortype", "ResourceNotFoundException")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"Type":"User","message":"Function not found"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"Configuration":%s,"Code":{"RepositoryType":"S3","Location":"%s/code/%s"}}`, conf, ts.URL, v)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	app := newTestApp(t, ts)
+	app.Masker().Add("n3w-pa55w0rd") // resolved from a secure source
+
+	changes, err := app.DiffVersionsChanges(ctx, "hello", &lambroll.VersionsDiffOption{From: "41", To: "current", Code: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Category+" "+c.Path)
+	}
+	expected := []string{
+		"config Environment.Variables.DB_PASS",
+		"config Environment.Variables.FOO",
+		"config MemorySize",
+		"code CodeSha256",
+		"code Files/index.js",
+		"code Files/lib/new.js",
+	}
+	if d := cmp.Diff(expected, got); d != "" {
+		t.Errorf("unexpected changes: %s", d)
+	}
+	// the old value of the rotated secret must be masked too
+	if c := changes[0]; c.Old == "old-pa55w0rd" || c.New == "n3w-pa55w0rd" || c.Old == c.New {
+		t.Errorf("secret values must be masked: %v -> %v", c.Old, c.New)
+	}
+
+	changes, err = app.DiffVersionsChanges(ctx, "hello", &lambroll.VersionsDiffOption{From: "41", To: "45", Ignore: ".MemorySize, .Environment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "CodeSha256" {
+		t.Errorf("unexpected changes with ignore: %v", changes)
+	}
+
+	if _, err := app.DiffVersionsChanges(ctx, "hello", &lambroll.VersionsDiffOption{From: "41", To: "99"}); err == nil {
+		t.Error("expected error for missing version")
+	}
+}
@@ -0,0 +1,175 @@
+package lambroll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// VersionsPruneOption represents options for PruneVersions()
+type VersionsPruneOption struct {
+	Keep        int    `help:"number of latest versions to keep" default:"0"`
+	OlderThan   string `help:"delete versions published before the time (e.g. 30d, 2w, 2024-01-02)" default:""`
+	DryRun      bool   `help:"dry run" default:"false"`
+	Parallelism int    `help:"number of concurrent requests to delete versions" default:"4"`
+}
+
+func (opt VersionsPruneOption) label() string {
+	if opt.DryRun {
+		return "**DRY RUN**"
+	}
+	return ""
+}
+
+// versionsPrunePolicy decides which versions to delete.
+// Versions pointed at by aliases (including routing weights) are always kept.
+type versionsPrunePolicy struct {
+	Keep      int       // number of latest versions to keep. 0 means no limit
+	OlderThan time.Time // delete versions published before the time only. zero means no limit
+}
+
+// selectVersions returns the published versions to delete
+func (p versionsPrunePolicy) selectVersions(published []publishedVersion, aliases map[string][]string) []publishedVersion {
+	var deletes []publishedVersion
+	for i, v := range published { // in descending order of the version number
+		if i < p.Keep {
+			log.Printf("[debug] version %s is one of the latest %d versions, keeping", v.Version, p.Keep)
+			continue
+		}
+		if !p.OlderThan.IsZero() && !v.LastModified.Before(p.OlderThan) {
+			log.Printf("[debug] version %s is published at %s, keeping", v.Version, v.LastModified.Format(time.RFC3339))
+			continue
+		}
+		if as := aliases[v.Version]; as != nil {
+			log.Printf("[info] version %s has alias %v, keeping", v.Version, as)
+			continue
+		}
+		deletes = append(deletes, v)
+	}
+	return deletes
+}
+
+// PruneVersions deletes old versions of the function
+func (app *App) PruneVersions(ctx context.Context, opt *VersionsPruneOption) error {
+	return app.pruneVersionsByOption(ctx, opt, os.Stdout)
+}
+
+// pruneVersionsByOption deletes old versions of the function. The versions to delete are printed to w in dry run.
+func (app *App) pruneVersionsByOption(ctx context.Context, opt *VersionsPruneOption, w io.Writer) error {
+	if opt.Keep <= 0 && opt.OlderThan == "" {
+		return fmt.Errorf("--keep or --older-than is required")
+	}
+	policy := versionsPrunePolicy{Keep: opt.Keep}
+	if opt.OlderThan != "" {
+		t, err := parseSince(opt.OlderThan, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+		policy.OlderThan = t
+	}
+	fn, err := app.loadFunction(app.functionFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to load function: %w", err)
+	}
+	return app.pruneVersions(ctx, *fn.FunctionName, policy, opt, w)
+}
+
+// deleteVersions deletes versions except the latest keepVersions versions and the versions with aliases
+func (app *App) deleteVersions(ctx context.Context, functionName string, keepVersions int) error {
+	if keepVersions <= 0 {
+		log.Printf("[info] specify --keep-versions")
+		return nil
+	}
+	opt := &VersionsPruneOption{Keep: keepVersions, Parallelism: 4}
+	return app.pruneVersions(ctx, functionName, versionsPrunePolicy{Keep: keepVersions}, opt, os.Stdout)
+}
+
+func (app *App) pruneVersions(ctx context.Context, name string, policy versionsPrunePolicy, opt *VersionsPruneOption, w io.Writer) error {
+	aliases, err := app.getAliases(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to get aliases: %w", err)
+	}
+	versions, err := app.listVersions(ctx, name)
+	if err != nil {
+		return err
+	}
+	published, err := newPublishedVersions(versions)
+	if err != nil {
+		return err
+	}
+	deletes := policy.selectVersions(published, aliases)
+	if len(deletes) == 0 {
+		log.Println("[info] no versions to delete")
+		return nil
+	}
+
+	log.Printf("[info] deleting %d versions of %d %s", len(deletes), len(published), opt.label())
+	if opt.DryRun {
+		vos := make(versionsOutputs, 0, len(deletes))
+		for _, v := range deletes {
+			vos = append(vos, versionsOutput{Version: v.Version, LastModified: v.LastModified})
+		}
+		fmt.Fprint(w, vos.Table())
+		return nil
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	var eg errgroup.Group
+	eg.SetLimit(max(opt.Parallelism, 1))
+	for _, v := range deletes {
+		v := v
+		eg.Go(func() error {
+			// continue to delete other versions on failure
+			if err := app.deleteFunctionVersionWithRetry(ctx, name, v.Version); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	eg.Wait()
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to delete %d of %d versions: %w", len(errs), len(deletes), errors.Join(errs...))
+	}
+	log.Printf("[info] %d versions are deleted", len(deletes))
+	return nil
+}
+
+// deleteFunctionVersionWithRetry deletes the version with retry on throttling
+func (app *App) deleteFunctionVersionWithRetry(ctx context.Context, name, version string) error {
+	retryer := retryPolicy.Start(ctx)
+	for retryer.Continue() {
+		_, err := app.lambda.DeleteFunction(ctx, &lambda.DeleteFunctionInput{
+			FunctionName: aws.String(name),
+			Qualifier:    aws.String(version),
+		}, func(o *lambda.Options) {
+			// retries on throttling are controlled by retryPolicy
+			o.RetryMaxAttempts = 1
+		})
+		if err != nil {
+			var tmr *types.TooManyRequestsException
+			if errors.As(err, &tmr) {
+				log.Printf("[debug] throttled to delete version %s, retrying", version)
+				continue
+			}
+			return fmt.Errorf("failed to delete version %s: %w", version, err)
+		}
+		log.Printf("[info] deleted function version: %s", version)
+		return nil
+	}
+	return fmt.Errorf("failed to delete version %s (max retries reached)", version)
+}
@@ -0,0 +1,168 @@
+package lambroll_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+// testPruneVersionsServer serves versions 1 to 8 published daily since 2024-01-01.
+// The alias current points at 8 with routing weight for 6, and the alias old points at 2.
+// Deleting version 4 is throttled for the first throttles requests (always when throttles < 0).
+func testPruneVersionsServer(t *testing.T, throttles int) (*httptest.Server, func() []string, func() int) {
+	var mu sync.Mutex
+	var deleted []string
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/functions/hello/aliases"):
+			fmt.Fprint(w, `{"Aliases":[
+				{"Name":"current","FunctionVersion":"8","RoutingConfig":{"AdditionalVersionWeights":{"6":0.1}}},
+				{"Name":"old","FunctionVersion":"2"}
+			]}`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/functions/hello/versions"):
+			versions := []map[string]string{{"Version": "$LATEST", "LastModified": "2024-01-09T00:00:00.000+0000"}}
+			for i := 1; i <= 8; i++ {
+				versions = append(versions, map[string]string{
+					"Version":      fmt.Sprint(i),
+					"LastModified": fmt.Sprintf("2024-01-%02dT00:00:00.000+0000", i),
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"Versions": versions})
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/functions/hello"):
+			v := r.URL.Query().Get("Qualifier")
+			mu.Lock()
+			defer mu.Unlock()
+			if v == "4" {
+				requests++
+			}
+			if v == "4" && (throttles < 0 || requests <= throttles) {
+				w.Header().Set("X-Amzn-Errortype", "TooManyRequestsException")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"Type":"User","message":"Rate Exceeded."}`)
+				return
+			}
+			deleted = append(deleted, v)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ts, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Strings(deleted)
+			return deleted
+		}, func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return requests
+		}
+}
+
+var testPruneVersionsCases = []struct {
+	name     string
+	opt      lambroll.VersionsPruneOption
+	expected []string
+	printed  []string // versions printed in dry run
+}{
+	{
+		name:     "keep",
+		opt:      lambroll.VersionsPruneOption{Keep: 2, Parallelism: 2},
+		expected: []string{"1", "3", "4", "5"},
+	},
+	{
+		name:     "older than",
+		opt:      lambroll.VersionsPruneOption{OlderThan: "2024-01-04T00:00:00Z", Parallelism: 2},
+		expected: []string{"1", "3"},
+	},
+	{
+		name:     "keep and older than",
+		opt:      lambroll.VersionsPruneOption{Keep: 6, OlderThan: "2024-01-04T00:00:00Z", Parallelism: 2},
+		expected: []string{"1"},
+	},
+	{
+		name:     "dry run",
+		opt:      lambroll.VersionsPruneOption{Keep: 1, DryRun: true},
+		expected: nil,
+		printed:  []string{"7", "5", "4", "3", "1"},
+	},
+}
+
+var testVersionRowRegexp = regexp.MustCompile(`(?m)^\|\s+(\d+)\s+\|`)
+
+func TestPruneVersions(t *testing.T) {
+	fnPath := filepath.Join(t.TempDir(), "function.json")
+	if err := os.WriteFile(fnPath, []byte(`{"FunctionName":"hello"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range testPruneVersionsCases {
+		t.Run(c.name, func(t *testing.T) {
+			ts, deleted, _ := testPruneVersionsServer(t, 1)
+			defer ts.Close()
+			ctx := context.Background()
+			app := newTestAppWithOption(t, ts, &lambroll.Option{Function: fnPath})
+			var buf bytes.Buffer
+			if err := app.PruneVersionsWithOutput(ctx, &c.opt, &buf); err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(c.expected, deleted()); d != "" {
+				t.Errorf("unexpected deleted versions: %s", d)
+			}
+			var printed []string
+			for _, m := range testVersionRowRegexp.FindAllStringSubmatch(buf.String(), -1) {
+				printed = append(printed, m[1])
+			}
+			if d := cmp.Diff(c.printed, printed); d != "" {
+				t.Errorf("unexpected printed versions: %s\n%s", d, buf.String())
+			}
+		})
+	}
+}
+
+func TestPruneVersionsThrottled(t *testing.T) {
+	defer lambroll.SetRetryPolicy(time.Millisecond, 10*time.Millisecond, 3)()
+	fnPath := filepath.Join(t.TempDir(), "function.json")
+	if err := os.WriteFile(fnPath, []byte(`{"FunctionName":"hello"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ts, deleted, requests := testPruneVersionsServer(t, -1)
+	defer ts.Close()
+	ctx := context.Background()
+	app := newTestAppWithOption(t, ts, &lambroll.Option{Function: fnPath})
+	err := app.PruneVersions(ctx, &lambroll.VersionsPruneOption{Keep: 2, Parallelism: 2})
+	if err == nil || !strings.Contains(err.Error(), "failed to delete 1 of 4 versions") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// retried by retryPolicy only, not multiplied by the retries of the SDK
+	if n := requests(); n != 3 {
+		t.Errorf("unexpected requests to delete the throttled version: %d", n)
+	}
+	if d := cmp.Diff([]string{"1", "3", "5"}, deleted()); d != "" {
+		t.Errorf("other versions must be deleted: %s", d)
+	}
+}
+
+func TestPruneVersionsRequiresPolicy(t *testing.T) {
+	ctx := context.Background()
+	app := newTestApp(t, nil)
+	if err := app.PruneVersions(ctx, &lambroll.VersionsPruneOption{}); err == nil {
+		t.Error("--keep or --older-than must be required")
+	}
+}
@@ -0,0 +1,50 @@
+package lambroll
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+func yamlToJSON(src []byte) ([]byte, error) {
+	var v any
+	if err := yaml.Unmarshal(src, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse yaml: %w", err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert yaml to json: %w", err)
+	}
+	return b, nil
+}
+
+func jsonToYAML(src []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(src, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse json: %w", err)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to convert json to yaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// convertDefinition converts JSON definition to the format (json, jsonnet or yaml)
+func convertDefinition(src []byte, format string, path string) ([]byte, error) {
+	switch format {
+	case "jsonnet":
+		return jsonToJsonnet(src, path)
+	case "yaml":
+		return jsonToYAML(src)
+	default:
+		return src, nil
+	}
+}
@@ -0,0 +1,36 @@
+package lambroll_test
+
+import (
+	"testing"
+
+	"github.com/fujiwara/lambroll"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testYAMLJSON = `{"Environment":{"Variables":{"FOO":"yes","NUM":"0123"}},"FunctionName":"hello","Layers":[],"MemorySize":128}`
+
+var testYAMLExpected = `Environment:
+  Variables:
+    FOO: "yes"
+    NUM: "0123"
+FunctionName: hello
+Layers: []
+MemorySize: 128
+`
+
+func TestYAMLConversion(t *testing.T) {
+	y, err := lambroll.JSONToYAML([]byte(testYAMLJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(testYAMLExpected, string(y)); diff != "" {
+		t.Errorf("unexpected yaml (-expected +got)\n%s", diff)
+	}
+	j, err := lambroll.YAMLToJSON(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(testYAMLJSON, string(j)); diff != "" {
+		t.Errorf("unexpected json (-expected +got)\n%s", diff)
+	}
+}